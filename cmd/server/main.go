@@ -5,6 +5,7 @@ import (
 
 	"archive/zip"
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io" // Needed for SSE io.EOF check
@@ -12,6 +13,7 @@ import (
 	"net/http/cookiejar" // Import cookiejar
 	"os"                 // For file path operations
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -24,6 +26,8 @@ import (
 	"nugs-dl/internal/broadcast"
 	"nugs-dl/internal/downloader"
 	"nugs-dl/internal/logger" // Import the new logger package
+	"nugs-dl/internal/monitor"
+	"nugs-dl/internal/notify"
 	"nugs-dl/internal/queue"
 	"nugs-dl/internal/worker"
 
@@ -41,6 +45,8 @@ var (
 	sharedHttpClient  *http.Client            // Global HTTP client
 	progressUpdates   chan api.ProgressUpdate // Keep using package name
 	messageHub        *broadcast.Hub          // Global broadcaster hub instance
+	artistMonitor     *monitor.Monitor        // Global artist monitor instance
+	notifier          *notify.Gotify          // Global Gotify notifier instance
 )
 
 func main() {
@@ -75,10 +81,14 @@ func main() {
 	queueManager = queue.NewQueueManager()
 	logger.Info("Queue Manager initialized.")
 
-	// Initialize the Downloader Service, passing channel AND queue manager
-	downloaderService = downloader.NewDownloader(currentConfig, sharedHttpClient, progressUpdates, queueManager)
+	// Initialize the Downloader Service, passing channel AND queue manager; it reads the current config on every use
+	downloaderService = downloader.NewDownloader(getCurrentConfig, sharedHttpClient, progressUpdates, queueManager)
 	logger.Info("Downloader Service initialized.")
 
+	// Initialize the Gotify notifier; it reads the current config on every send
+	notifier = notify.NewGotify(getCurrentConfig)
+	downloaderService.Notifier = notifier
+
 	// Initialize and run the Broadcaster Hub
 	messageHub = broadcast.NewHub()
 	go messageHub.Run()
@@ -106,7 +116,12 @@ func main() {
 	}()
 
 	// Start the Background Worker
-	worker.StartWorker(queueManager, downloaderService, messageHub)
+	worker.StartWorker(queueManager, downloaderService, messageHub, notifier)
+
+	// Start the Artist Monitor; it reads the current config on every pass
+	artistMonitor = monitor.NewMonitor(getCurrentConfig, downloaderService, queueManager, messageHub)
+	artistMonitor.Notifier = notifier
+	artistMonitor.Start()
 
 	router := gin.Default()
 
@@ -120,11 +135,33 @@ func main() {
 		apiGroup.GET("/downloads", getDownloadsHandler)             // New endpoint for list
 		apiGroup.GET("/downloads/:jobId", getDownloadJobHandler)    // New endpoint for specific job
 		apiGroup.DELETE("/downloads/:jobId", removeDownloadHandler) // Added DELETE route
+		apiGroup.POST("/downloads/:jobId/run", runDryRunHandler)     // Queue the real download for a dry run
 		apiGroup.GET("/status-stream", sseStatusHandler)            // SSE endpoint
 		// History endpoint
 		apiGroup.GET("/history", getHistoryHandler)                 // New endpoint for completed downloads
 		// File download endpoint
 		apiGroup.GET("/download/:jobId", downloadFileHandler)       // New endpoint to download completed files
+		// Path template preview
+		apiGroup.POST("/templates/preview", previewTemplateHandler)
+		// Track listing / selection preview
+		apiGroup.POST("/tracks/preview", previewTracksHandler)
+		// Inspect URLs before enqueueing them
+		apiGroup.POST("/preview", previewURLsHandler)
+		// Audio formats offered per track of a release
+		apiGroup.GET("/formats/:containerId", probeFormatsHandler)
+		// Count the releases a filtered artist job would download
+		apiGroup.POST("/artists/preview", previewArtistFilterHandler)
+		// Browse an artist's catalog and queue hand-picked releases
+		apiGroup.GET("/artists/:artistId/catalog", artistCatalogHandler)
+		apiGroup.POST("/artists/:artistId/catalog/enqueue", enqueueCatalogHandler)
+		// Collection gaps of an artist, as JSON or CSV, and queueing downloads to fill them
+		apiGroup.GET("/artists/:artistId/gaps", artistGapsHandler)
+		apiGroup.POST("/artists/:artistId/gaps/enqueue", enqueueGapsHandler)
+		// Artist monitoring
+		apiGroup.POST("/monitor/check", monitorCheckHandler)
+		apiGroup.GET("/monitor/status", monitorStatusHandler)
+		// Send a test notification to verify the Gotify setup
+		apiGroup.POST("/notifications/test", testNotificationHandler)
 	}
 
 	// Handle SPA routing and static files: for any route not matched by API,
@@ -211,6 +248,32 @@ func updateConfigHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video Format (must be 1-5)"})
 		return
 	}
+	for name, tmpl := range map[string]string{
+		"albumFolderTemplate":    updatedConfig.AlbumFolderTemplate,
+		"trackFileTemplate":      updatedConfig.TrackFileTemplate,
+		"playlistFolderTemplate": updatedConfig.PlaylistFolderTemplate,
+		"videoFileTemplate":      updatedConfig.VideoFileTemplate,
+	} {
+		if tmpl == "" {
+			continue
+		}
+		if err := downloader.ValidatePathTemplate(tmpl); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s: %v", name, err)})
+			return
+		}
+	}
+	if !downloader.IsValidLayout(updatedConfig.TrackLayout) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid trackLayout (must be one of %v)", downloader.ValidLayouts)})
+		return
+	}
+	if !downloader.IsValidVideoCodec(updatedConfig.VideoCodec) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid videoCodec (must be empty, h264 or hevc)"})
+		return
+	}
+	if !downloader.IsValidSingleFileMode(updatedConfig.SingleFile) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid singleFile (must be empty, alongside or replace)"})
+		return
+	}
 	// Add more validation as needed (e.g., for OutPath)
 	//----------------------------------------------------------------------
 
@@ -245,6 +308,11 @@ func addDownloadHandler(c *gin.Context) {
 		return
 	}
 
+	if msg := validateDownloadOptions(req.Options); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
 	var results []api.AddDownloadResponseItem
 	var addedJobs []*api.DownloadJob // Collect successfully added jobs
 	for _, url := range req.Urls {
@@ -372,11 +440,53 @@ func removeDownloadHandler(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// runDryRunHandler handles POST /api/downloads/:jobId/run requests. It queues the real
+// download for a finished dry run, using the same URL and options.
+func runDryRunHandler(c *gin.Context) {
+	jobID := c.Param("jobId")
+
+	planJob, found := queueManager.GetJob(jobID)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Job with ID %s not found", jobID)})
+		return
+	}
+	if !planJob.DryRun || planJob.Status != api.StatusComplete {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Job %s is not a completed dry run", jobID)})
+		return
+	}
+	// The downloader reads the same live config, so the run can't turn into a dry run again
+	if cfg := getCurrentConfig(); cfg != nil && cfg.DryRun {
+		c.JSON(http.StatusConflict, gin.H{"error": "dryRun is enabled in the config, disable it to run downloads"})
+		return
+	}
+
+	opts := planJob.Options
+	opts.DryRun = false
+	job, err := queueManager.AddJob(planJob.OriginalUrl, opts)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Failed to add job to queue: %v", err)})
+		return
+	}
+	messageHub.BroadcastJobAdded(job)
+	c.JSON(http.StatusAccepted, api.AddDownloadResponseItem{Url: job.OriginalUrl, JobID: job.ID})
+}
+
 // getHistoryHandler handles GET /api/history requests (list completed downloads)
+// With ?degraded=true only jobs where a track fell back from the preferred format are returned.
 func getHistoryHandler(c *gin.Context) {
 	// Retrieve completed jobs from the manager
 	completedJobs := queueManager.GetCompletedJobs()
 
+	if c.Query("degraded") == "true" {
+		degradedJobs := make([]*api.DownloadJob, 0)
+		for _, job := range completedJobs {
+			if job.Degraded {
+				degradedJobs = append(degradedJobs, job)
+			}
+		}
+		completedJobs = degradedJobs
+	}
+
 	// Return the list (might be empty)
 	c.JSON(http.StatusOK, completedJobs)
 }
@@ -405,7 +515,16 @@ func downloadFileHandler(c *gin.Context) {
 		// and that it represents the album/folder name.
 		// Need to ensure job.Title is set to a filesystem-safe name.
 		// For now, we'll use job.Title if available, otherwise fallback to scanning.
-		if job.Title != "" {
+		if info, err := os.Stat(job.OutputPath); err == nil && !info.IsDir() {
+			// Video jobs record their file; it is served as is rather than zipped
+			c.FileAttachment(job.OutputPath, filepath.Base(job.OutputPath))
+			return
+		}
+		if job.OutputPath != "" {
+			// Folder recorded by the downloader after rendering the path templates
+			albumPath = job.OutputPath
+			sanitizedTitle = filepath.Base(job.OutputPath)
+		} else if job.Title != "" {
 			albumPath = filepath.Join(downloadPath, sanitizeForFilename(job.Title))
 		} else {
 			// Fallback: if job.Title is not set, try to find the first available album
@@ -477,6 +596,440 @@ func downloadFileHandler(c *gin.Context) {
 	c.Data(http.StatusOK, "application/zip", zipBuffer)
 }
 
+// previewTemplateHandler handles POST /api/templates/preview requests.
+// It renders a path template against the metadata of a real container.
+func previewTemplateHandler(c *gin.Context) {
+	var req api.TemplatePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	containerID, err := resolveContainerID(req.ContainerID, req.Url)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paths, err := downloaderService.PreviewPathTemplate(req.Kind, req.Template, containerID)
+	if err != nil {
+		logger.Warn("[previewTemplateHandler] Template preview failed", "kind", req.Kind, "containerID", containerID, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, api.TemplatePreviewResponse{
+		Kind:        req.Kind,
+		Template:    req.Template,
+		ContainerID: containerID,
+		Paths:       paths,
+	})
+}
+
+// previewTracksHandler handles POST /api/tracks/preview requests.
+// It lists a release's tracks and marks which ones a selection would download.
+func previewTracksHandler(c *gin.Context) {
+	var req api.TrackPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if !downloader.IsValidLayout(req.Layout) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid layout (must be one of %v)", downloader.ValidLayouts)})
+		return
+	}
+	if err := downloader.ValidateTrackSelection(req.Selection); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid selection: " + err.Error()})
+		return
+	}
+
+	containerID, err := resolveContainerID(req.ContainerID, req.Url)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preview, err := downloaderService.PreviewTracks(containerID, req.Selection, req.Layout)
+	if err != nil {
+		logger.Warn("[previewTracksHandler] Track preview failed", "containerID", containerID, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// previewURLsHandler handles POST /api/preview requests.
+// It describes what each URL points to without creating jobs.
+func previewURLsHandler(c *gin.Context) {
+	var req api.URLPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	previews := make([]api.URLPreview, 0, len(req.Urls))
+	for _, u := range req.Urls {
+		previews = append(previews, downloaderService.PreviewURL(strings.TrimSpace(u)))
+	}
+	c.JSON(http.StatusOK, previews)
+}
+
+// probeFormatsHandler handles GET /api/formats/:containerId requests.
+// It lists the audio formats offered per track; ?refresh=true bypasses the cache.
+func probeFormatsHandler(c *gin.Context) {
+	containerID := c.Param("containerId")
+	if _, err := strconv.Atoi(containerID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "containerId must be numeric"})
+		return
+	}
+	report, err := downloaderService.ProbeFormats(containerID, c.Query("refresh") == "true")
+	if err != nil {
+		logger.Warn("[probeFormatsHandler] Format probe failed", "containerID", containerID, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// validateDownloadOptions checks the options of new jobs. It returns a message
+// describing the first problem, or an empty string if they're valid.
+func validateDownloadOptions(opts api.DownloadOptions) string {
+	if !downloader.IsValidLayout(opts.Layout) {
+		return fmt.Sprintf("Invalid layout (must be one of %v)", downloader.ValidLayouts)
+	}
+	if !downloader.IsValidSingleFileMode(opts.SingleFile) {
+		return "Invalid singleFile (must be empty, alongside or replace)"
+	}
+	if err := downloader.ValidateTrackSelection(opts.Selection); err != nil {
+		return "Invalid selection: " + err.Error()
+	}
+	for _, f := range opts.Formats {
+		if !(f >= 1 && f <= 5) {
+			return fmt.Sprintf("Invalid format %d in formats (must be 1-5)", f)
+		}
+	}
+	// Repeats of the primary format or of each other are skipped by the downloader, which
+	// also knows the primary format when it comes from the config or an artist override
+	for _, f := range opts.ExtraFormats {
+		if !(f >= 1 && f <= 5) {
+			return fmt.Sprintf("Invalid format %d in extraFormats (must be 1-5)", f)
+		}
+	}
+	if !(opts.Format >= 0 && opts.Format <= 5) {
+		return "Invalid format (must be 1-5, or 0 for the configured format)"
+	}
+	if !(opts.VideoFormat >= 0 && opts.VideoFormat <= 5) {
+		return "Invalid videoFormat (must be 1-5, or 0 for the configured format)"
+	}
+	if err := downloader.ValidateArtistFilter(opts.ArtistFilter); err != nil {
+		return "Invalid artistFilter: " + err.Error()
+	}
+	return ""
+}
+
+// previewArtistFilterHandler reports which releases of an artist a filter picks,
+// so a backfill can be checked before its job is queued.
+func previewArtistFilterHandler(c *gin.Context) {
+	var req api.ArtistFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	artistID := req.ArtistID
+	if artistID == "" {
+		id, urlType := downloader.CheckUrl(req.Url)
+		if urlType != downloader.ArtistUrl {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Either artistId or an artist url is required"})
+			return
+		}
+		artistID = id
+	}
+	if _, err := strconv.Atoi(artistID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "artistId must be numeric"})
+		return
+	}
+	if err := downloader.ValidateArtistFilter(req.Filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filter: " + err.Error()})
+		return
+	}
+
+	preview, err := downloaderService.PreviewArtistFilter(artistID, req.Filter, req.OutPath)
+	if err != nil {
+		logger.Warn("[previewArtistFilterHandler] Artist filter preview failed", "artistID", artistID, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// artistCatalogHandler returns a page of an artist's releases with their library state.
+// Query parameters: page, pageSize (max 200), sort (date, title or venue), order
+// (asc or desc; dates default to newest first), q to search and refresh=true.
+func artistCatalogHandler(c *gin.Context) {
+	artistID := c.Param("artistId")
+	if _, err := strconv.Atoi(artistID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "artistId must be numeric"})
+		return
+	}
+	q := downloader.CatalogQuery{
+		Sort:    c.DefaultQuery("sort", downloader.CatalogSortDate),
+		Search:  c.Query("q"),
+		Refresh: c.Query("refresh") == "true",
+	}
+	if !downloader.IsValidCatalogSort(q.Sort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort (must be date, title or venue)"})
+		return
+	}
+	switch c.Query("order") {
+	case "":
+		q.Desc = q.Sort == downloader.CatalogSortDate
+	case "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order (must be asc or desc)"})
+		return
+	}
+	var err error
+	if q.Page, err = strconv.Atoi(c.DefaultQuery("page", "1")); err != nil || q.Page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive number"})
+		return
+	}
+	if q.PageSize, err = strconv.Atoi(c.DefaultQuery("pageSize", "50")); err != nil || q.PageSize < 1 || q.PageSize > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pageSize must be between 1 and 200"})
+		return
+	}
+
+	catalog, err := downloaderService.ArtistCatalog(artistID, q)
+	if err != nil {
+		logger.Warn("[artistCatalogHandler] Failed to list artist catalog", "artistID", artistID, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, catalog)
+}
+
+// enqueueCatalogHandler queues a download job for each picked release of an artist.
+func enqueueCatalogHandler(c *gin.Context) {
+	var req api.CatalogEnqueueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if len(req.ContainerIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "containerIds must not be empty"})
+		return
+	}
+	if msg := validateDownloadOptions(req.Options); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	var results []api.AddDownloadResponseItem
+	for _, id := range req.ContainerIDs {
+		url := downloader.ReleaseURL(id)
+		job, err := queueManager.AddJob(url, req.Options)
+		if err != nil {
+			logger.Error("Error adding catalog release to queue", "artistID", c.Param("artistId"), "containerID", id, "error", err)
+			results = append(results, api.AddDownloadResponseItem{Url: url, Error: fmt.Sprintf("Failed to add job to queue: %v", err)})
+			continue
+		}
+		results = append(results, api.AddDownloadResponseItem{Url: url, JobID: job.ID})
+		messageHub.BroadcastJobAdded(job)
+	}
+	c.JSON(http.StatusAccepted, results)
+}
+
+// artistGapsHandler reports the releases of an artist missing from the library, owned
+// only in a lower quality, or missing tracks. ?format=csv exports the report as CSV.
+func artistGapsHandler(c *gin.Context) {
+	artistID := c.Param("artistId")
+	if _, err := strconv.Atoi(artistID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "artistId must be numeric"})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format (must be json or csv)"})
+		return
+	}
+
+	report, err := downloaderService.ArtistGaps(artistID, c.Query("refresh") == "true")
+	if err != nil {
+		logger.Warn("[artistGapsHandler] Failed to build gap report", "artistID", artistID, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"containerId", "date", "title", "venue", "gaps", "format", "expectedTracks", "haveTracks", "missingTracks", "path", "url"})
+	for _, item := range report.Items {
+		missingTracks := strings.Join(item.MissingTracks, ";")
+		if item.MissingTracksUnknown {
+			missingTracks = "unknown"
+		}
+		w.Write([]string{
+			strconv.Itoa(item.ContainerID),
+			item.Date,
+			item.Title,
+			item.Venue,
+			strings.Join(item.Gaps, ";"),
+			item.FormatName,
+			strconv.Itoa(item.ExpectedTracks),
+			strconv.Itoa(item.HaveTracks),
+			missingTracks,
+			item.Path,
+			item.Url,
+		})
+	}
+	w.Flush()
+	filename := sanitizeForFilename(report.ArtistName) + " - gaps.csv"
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// enqueueGapsHandler queues a job for each release with the requested kinds of gaps
+// (only missing releases by default).
+func enqueueGapsHandler(c *gin.Context) {
+	artistID := c.Param("artistId")
+	if _, err := strconv.Atoi(artistID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "artistId must be numeric"})
+		return
+	}
+	var req api.GapEnqueueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if msg := validateDownloadOptions(req.Options); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	gaps := map[string]bool{api.GapMissing: len(req.Gaps) == 0}
+	for _, g := range req.Gaps {
+		if g != api.GapMissing && g != api.GapLowerQuality && g != api.GapMissingTracks {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid gap kind %q (must be missing, lower_quality or missing_tracks)", g)})
+			return
+		}
+		gaps[g] = true
+	}
+
+	report, err := downloaderService.LastArtistGaps(artistID) // Usually the report the user just looked at
+	if err != nil {
+		logger.Warn("[enqueueGapsHandler] Failed to build gap report", "artistID", artistID, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	results := []api.AddDownloadResponseItem{}
+	for _, item := range report.Items {
+		opts, ok := downloader.GapJobOptions(item, gaps, req.Options)
+		if !ok {
+			continue
+		}
+		job, err := queueManager.AddJob(item.Url, opts)
+		if err != nil {
+			logger.Error("Error adding gap release to queue", "artistID", artistID, "containerID", item.ContainerID, "error", err)
+			results = append(results, api.AddDownloadResponseItem{Url: item.Url, Error: fmt.Sprintf("Failed to add job to queue: %v", err)})
+			continue
+		}
+		results = append(results, api.AddDownloadResponseItem{Url: item.Url, JobID: job.ID})
+		messageHub.BroadcastJobAdded(job)
+	}
+	logger.Info("[enqueueGapsHandler] Queued downloads to fill collection gaps", "artistID", artistID, "gaps", req.Gaps, "jobs", len(results))
+	c.JSON(http.StatusAccepted, results)
+}
+
+// testNotificationHandler sends a test notification with the configured Gotify settings.
+// It is sent even while notifications are turned off, so the setup can be checked first.
+func testNotificationHandler(c *gin.Context) {
+	cfg := getCurrentConfig()
+	if err := notifier.Send("nugs-dl test notification", "Notifications are set up correctly.", cfg.GotifyPriority, ""); err != nil {
+		logger.Warn("[testNotificationHandler] Test notification failed", "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	resp := gin.H{"message": "Test notification sent"}
+	if !cfg.Notifications {
+		resp["warning"] = "notifications is false in the config, so no other notifications will be sent"
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// getCurrentConfig returns the current configuration under the config lock.
+func getCurrentConfig() *appConfig.AppConfig {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return currentConfig
+}
+
+// monitorCheckHandler checks monitored artists for new releases in the background.
+// An artistId query parameter limits the check to one artist.
+func monitorCheckHandler(c *gin.Context) {
+	artistID := 0
+	if idStr := c.Query("artistId"); idStr != "" {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "artistId must be numeric"})
+			return
+		}
+		found := false
+		for _, a := range getCurrentConfig().Artists {
+			if a.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Artist %d is not configured", id)})
+			return
+		}
+		artistID = id
+	}
+	if artistMonitor.Status().Checking {
+		c.JSON(http.StatusConflict, gin.H{"error": monitor.ErrCheckRunning.Error()})
+		return
+	}
+
+	go func() {
+		var err error
+		if artistID != 0 {
+			err = artistMonitor.CheckArtist(artistID)
+		} else {
+			err = artistMonitor.CheckNow()
+		}
+		if err != nil {
+			logger.Warn("[monitorCheckHandler] Monitor check failed", "artistID", artistID, "error", err)
+		}
+	}()
+	c.JSON(http.StatusAccepted, gin.H{"message": "Monitor check started"})
+}
+
+// monitorStatusHandler reports monitor settings, last checks and recent finds.
+func monitorStatusHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, artistMonitor.Status())
+}
+
+// resolveContainerID returns the container ID given directly or extracted from a release URL.
+func resolveContainerID(containerID, rawUrl string) (string, error) {
+	if containerID != "" {
+		return containerID, nil
+	}
+	if rawUrl == "" {
+		return "", fmt.Errorf("either containerId or url is required")
+	}
+	id, urlType := downloader.CheckUrl(rawUrl)
+	switch urlType {
+	case downloader.ReleaseUrl, downloader.ExclusiveLivestreamUrl, downloader.WatchExclusiveLivestreamUrl,
+		downloader.MyWebcastLibUrl, downloader.WatchReleaseUrl:
+		return id, nil
+	}
+	return "", fmt.Errorf("URL is not a release URL: %s", rawUrl)
+}
+
 // sanitizeForFilename removes or replaces characters that are invalid in filenames
 func sanitizeForFilename(filename string) string {
 	// Replace invalid characters with safe alternatives
@@ -24,6 +24,29 @@ type ArtistConfig struct {
 	// Add other overridable fields as needed
 }
 
+// TranscodeProfile describes a derived copy produced from downloaded tracks with ffmpeg.
+type TranscodeProfile struct {
+	Name           string `yaml:"name"`
+	Codec          string `yaml:"codec"`                    // ffmpeg audio encoder, e.g. libopus, libmp3lame, aac
+	Bitrate        string `yaml:"bitrate,omitempty"`        // Target bitrate, e.g. "160k"
+	Quality        string `yaml:"quality,omitempty"`        // VBR quality (-q:a), e.g. "0" for MP3 V0
+	Extension      string `yaml:"extension"`                // Output extension, e.g. ".opus"
+	OutPath        string `yaml:"outPath"`                  // Output root for this profile
+	FolderTemplate string `yaml:"folderTemplate,omitempty"` // Album folder template; empty uses albumFolderTemplate
+	SourceFormats  []int  `yaml:"sourceFormats,omitempty"`  // Source formats to transcode; empty means lossless (1, 2, 3)
+}
+
+// VideoEncodeProfile describes a re-encoded copy of each downloaded video.
+type VideoEncodeProfile struct {
+	Name         string `yaml:"name"`
+	Codec        string `yaml:"codec"`                  // ffmpeg video encoder, e.g. libx264, libx265
+	MaxHeight    int    `yaml:"maxHeight,omitempty"`    // Downscale to at most this height; 0 keeps the resolution
+	CRF          int    `yaml:"crf,omitempty"`          // Constant rate factor; 0 uses the encoder default
+	Preset       string `yaml:"preset,omitempty"`       // Encoder preset, e.g. "medium"
+	AudioBitrate string `yaml:"audioBitrate,omitempty"` // Re-encode audio to AAC at this bitrate; empty copies the audio
+	Replace      bool   `yaml:"replace"`                // Replace the original instead of writing "<name> [profile].mp4" beside it
+}
+
 // AppConfig holds the entire application configuration, loaded from config.yaml.
 type AppConfig struct {
 	Email                  string `yaml:"email"`
@@ -32,6 +55,9 @@ type AppConfig struct {
 	VideoFormat            int    `yaml:"videoFormat"` // Global default: 1: 480p, 2: 720p, 3: 1080p, 4: 1440p, 5: 4K/Best
 	OutPath                string `yaml:"outPath"`
 	LiveVideoPath          string `yaml:"liveVideoPath,omitempty"`
+	LiveMaxDurationMinutes int    `yaml:"liveMaxDurationMinutes,omitempty"` // Stop recording a live stream after this long (default 360)
+	LiveLeadMinutes        int    `yaml:"liveLeadMinutes,omitempty"`        // Start scheduled livestream captures this long before the event
+	LiveWaitMinutes        int    `yaml:"liveWaitMinutes,omitempty"`        // Keep retrying a stream that isn't up yet this long past the start (default 60)
 	Token                  string `yaml:"token,omitempty"`
 	UseFfmpegEnvVar        bool   `yaml:"useFfmpegEnvVar"`
 
@@ -45,6 +71,35 @@ type AppConfig struct {
 	SkipVideos             bool   `yaml:"skipVideos"`
 	SkipChapters           bool   `yaml:"skipChapters"`
 
+	// Video variant selection. videoFormat caps the resolution (5 = no cap).
+	VideoCodec             string  `yaml:"videoCodec,omitempty"`          // Preferred codec: h264 or hevc; empty means any
+	VideoMaxFrameRate      float64 `yaml:"videoMaxFrameRate,omitempty"`   // Skip variants above this frame rate; 0 means no cap
+	VideoMaxBitrateKbps    int     `yaml:"videoMaxBitrateKbps,omitempty"` // Skip variants above this bitrate; 0 means no cap
+	VideoEncodeProfiles    []VideoEncodeProfile `yaml:"videoEncodeProfiles,omitempty"` // Re-encoded copies made after each video downloads
+	PackageVideos          bool   `yaml:"packageVideos"`      // Write <name>-poster.jpg, <name>-fanart.jpg and <name>.nfo beside each video
+	SplitVideoChapters     bool   `yaml:"splitVideoChapters"` // Cut video audio at chapters into per-song tracks in the audio library
+	SplitVideoClips        bool   `yaml:"splitVideoClips"`    // Cut videos at chapters into per-song MP4 clips beside the video
+	ExtractVideoAudio      bool   `yaml:"extractVideoAudio"`  // Write the full audio of each video as one file into the audio library
+
+	// Path templates (Go text/template). Empty means the built-in default layout.
+	AlbumFolderTemplate    string `yaml:"albumFolderTemplate,omitempty"`
+	TrackFileTemplate      string `yaml:"trackFileTemplate,omitempty"` // Extension is appended automatically
+	PlaylistFolderTemplate string `yaml:"playlistFolderTemplate,omitempty"`
+	VideoFileTemplate      string `yaml:"videoFileTemplate,omitempty"` // Extension is appended automatically
+
+	TrackLayout            string `yaml:"trackLayout,omitempty"` // sequential, disc-folders, disc-track, set-prefixed
+	SkipTags               bool   `yaml:"skipTags"`              // Don't write title/artist/album/disc/track tags
+	WriteM3U8              bool   `yaml:"writeM3u8"`             // Write an .m3u8 playlist into each album/playlist folder
+	WriteCue               bool   `yaml:"writeCue"`              // Write a .cue sheet with set markers into each album folder
+	WriteMetadataJSON      bool   `yaml:"writeMetadataJson"`     // Archive the raw container metadata as nugs.json beside the music
+	WriteSetlist           bool   `yaml:"writeSetlist"`          // Write a human-readable info.txt setlist beside the music
+	SingleFile             string `yaml:"singleFile,omitempty"`  // "alongside" or "replace": also render each release as one file with chapters
+
+	FormatFallback         []int  `yaml:"formatFallback,omitempty"` // Ordered format preference, e.g. [3, 2, 1]; empty derives from format
+	StrictQuality          bool   `yaml:"strictQuality"`            // Fail tracks instead of degrading to a format outside the preference (or to lossy)
+
+	TranscodeProfiles      []TranscodeProfile `yaml:"transcodeProfiles,omitempty"` // Derived copies made after each show downloads
+
 	MaxConcurrentDownloads int    `yaml:"maxConcurrentDownloads"`
 	MaxRetries             int    `yaml:"maxRetries"`
 	RetryDelaySeconds      int    `yaml:"retryDelaySeconds"`
@@ -99,6 +154,10 @@ func getConfigPath() string {
 	return configFileName
 }
 
+// StatePath returns the path of a state file kept beside the config file.
+func StatePath(fileName string) string {
+	return filepath.Join(filepath.Dir(getConfigPath()), fileName)
+}
 
 // LoadConfig reads the configuration file (config.yaml) and returns the AppConfig struct.
 // It applies default values for missing or invalid fields.
@@ -160,6 +219,27 @@ func LoadConfig() (*AppConfig, error) {
 		cfg.RetryDelaySeconds = defaultRetryDelay
 	}
 
+	switch cfg.TrackLayout {
+	case "", "sequential", "disc-folders", "disc-track", "set-prefixed":
+	default:
+		logger.Error("Invalid trackLayout", "trackLayout", cfg.TrackLayout)
+		return nil, fmt.Errorf("config error: trackLayout must be one of sequential, disc-folders, disc-track, set-prefixed, got '%s'", cfg.TrackLayout)
+	}
+
+	switch cfg.VideoCodec {
+	case "", "h264", "hevc":
+	default:
+		logger.Error("Invalid videoCodec", "videoCodec", cfg.VideoCodec)
+		return nil, fmt.Errorf("config error: videoCodec must be empty, h264 or hevc, got '%s'", cfg.VideoCodec)
+	}
+
+	switch cfg.SingleFile {
+	case "", "alongside", "replace":
+	default:
+		logger.Error("Invalid singleFile", "singleFile", cfg.SingleFile)
+		return nil, fmt.Errorf("config error: singleFile must be empty, alongside or replace, got '%s'", cfg.SingleFile)
+	}
+
 	if cfg.LogLevel == "" {
 		cfg.LogLevel = defaultLogLevel
 	}
@@ -209,6 +289,29 @@ func LoadConfig() (*AppConfig, error) {
 		// ensure dependent values are at their "disabled" or non-interfering defaults.
 	}
 	
+	for _, f := range cfg.FormatFallback {
+		if !(f >= 1 && f <= 5) {
+			logger.Error("Invalid format in formatFallback", "format", f)
+			return nil, fmt.Errorf("config error: formatFallback entries must be between 1 and 5, got %d", f)
+		}
+	}
+
+	if err := validateTranscodeProfiles(cfg.TranscodeProfiles); err != nil {
+		logger.Error("Invalid transcodeProfiles", "error", err)
+		return nil, fmt.Errorf("config error: %w", err)
+	}
+	if err := validateVideoEncodeProfiles(cfg.VideoEncodeProfiles); err != nil {
+		logger.Error("Invalid videoEncodeProfiles", "error", err)
+		return nil, fmt.Errorf("config error: %w", err)
+	}
+	for i, profile := range cfg.TranscodeProfiles {
+		cfg.TranscodeProfiles[i].OutPath, err = filepath.Abs(profile.OutPath)
+		if err != nil {
+			logger.Error("Failed to get absolute path for transcode profile outPath", "profile", profile.Name, "path", profile.OutPath, "error", err)
+			return nil, fmt.Errorf("failed to get absolute path for transcode profile outPath: %w", err)
+		}
+	}
+
 	// Validate artist-specific formats if provided
 	for i, artist := range cfg.Artists {
 		if artist.Format != 0 && !(artist.Format >= 1 && artist.Format <= 5) {
@@ -245,6 +348,20 @@ func SaveConfig(cfg *AppConfig) error {
 		logger.Error("SaveConfig validation failed: global video Format invalid", "videoFormat", cfg.VideoFormat)
 		return fmt.Errorf("video Format must be between 1 and 5, got %d", cfg.VideoFormat)
 	}
+	for _, f := range cfg.FormatFallback {
+		if !(f >= 1 && f <= 5) {
+			logger.Error("SaveConfig validation failed: formatFallback entry invalid", "format", f)
+			return fmt.Errorf("formatFallback entries must be between 1 and 5, got %d", f)
+		}
+	}
+	if err := validateTranscodeProfiles(cfg.TranscodeProfiles); err != nil {
+		logger.Error("SaveConfig validation failed: transcodeProfiles invalid", "error", err)
+		return err
+	}
+	if err := validateVideoEncodeProfiles(cfg.VideoEncodeProfiles); err != nil {
+		logger.Error("SaveConfig validation failed: videoEncodeProfiles invalid", "error", err)
+		return err
+	}
 	// Validate artist-specific formats
 	for _, artist := range cfg.Artists {
 		if artist.Format != 0 && !(artist.Format >= 1 && artist.Format <= 5) {
@@ -273,8 +390,85 @@ func SaveConfig(cfg *AppConfig) error {
 	return nil
 }
 
-// GetEffectiveArtistConfig is a placeholder for logic to merge global and artist-specific settings.
-// func (c *AppConfig) GetEffectiveArtistConfig(artistNameOrID string) (*ArtistConfig, error) {
-// 	 // TODO: Implement logic to find artist by name/ID and merge with c.Download defaults.
-// 	 return nil, errors.New("not implemented")
-// }
+// validateTranscodeProfiles checks that every profile is usable and names are unique.
+func validateTranscodeProfiles(profiles []TranscodeProfile) error {
+	seen := make(map[string]bool)
+	for _, p := range profiles {
+		if p.Name == "" {
+			return errors.New("transcode profiles must have a name")
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate transcode profile name '%s'", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Codec == "" {
+			return fmt.Errorf("transcode profile '%s' must set a codec", p.Name)
+		}
+		if !strings.HasPrefix(p.Extension, ".") || len(p.Extension) < 2 {
+			return fmt.Errorf("transcode profile '%s' must set an extension such as .opus, got '%s'", p.Name, p.Extension)
+		}
+		if p.OutPath == "" {
+			return fmt.Errorf("transcode profile '%s' must set an outPath", p.Name)
+		}
+		for _, f := range p.SourceFormats {
+			if !(f >= 1 && f <= 5) {
+				return fmt.Errorf("transcode profile '%s': sourceFormats entries must be between 1 and 5, got %d", p.Name, f)
+			}
+		}
+	}
+	return nil
+}
+
+// validateVideoEncodeProfiles checks that every profile is usable and names are unique.
+// At most one profile may replace the original video.
+func validateVideoEncodeProfiles(profiles []VideoEncodeProfile) error {
+	seen := make(map[string]bool)
+	replacing := 0
+	for _, p := range profiles {
+		if p.Name == "" {
+			return errors.New("video encode profiles must have a name")
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate video encode profile name '%s'", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Codec == "" {
+			return fmt.Errorf("video encode profile '%s' must set a codec", p.Name)
+		}
+		if p.MaxHeight < 0 || p.CRF < 0 {
+			return fmt.Errorf("video encode profile '%s': maxHeight and crf must not be negative", p.Name)
+		}
+		if p.Replace {
+			replacing++
+		}
+	}
+	if replacing > 1 {
+		return errors.New("only one video encode profile may replace the original")
+	}
+	return nil
+}
+
+// GetEffectiveArtistConfig returns the settings of an artist with its overrides merged
+// over the global defaults. It returns false if the artist isn't configured.
+func (c *AppConfig) GetEffectiveArtistConfig(artistID int) (ArtistConfig, bool) {
+	for _, a := range c.Artists {
+		if a.ID != artistID {
+			continue
+		}
+		if a.Format == 0 {
+			a.Format = c.Format
+		}
+		if a.VideoFormat == 0 {
+			a.VideoFormat = c.VideoFormat
+		}
+		if a.OutPath == "" {
+			a.OutPath = c.OutPath
+		}
+		if a.Notifications == nil {
+			notifications := c.Notifications
+			a.Notifications = &notifications
+		}
+		return a, true
+	}
+	return ArtistConfig{}, false
+}
@@ -0,0 +1,184 @@
+package downloader
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"nugs-dl/pkg/api"
+)
+
+// Media kinds an artist filter can pick.
+const (
+	ArtistMediaBoth  = ""
+	ArtistMediaAudio = "audio"
+	ArtistMediaVideo = "video"
+)
+
+// parsedArtistFilter is an artist filter with its dates parsed.
+type parsedArtistFilter struct {
+	*api.ArtistFilter
+	from, to time.Time // Zero when not set
+}
+
+// parseArtistFilter checks a filter and parses its dates.
+func parseArtistFilter(f *api.ArtistFilter) (*parsedArtistFilter, error) {
+	p := &parsedArtistFilter{ArtistFilter: f}
+	var err error
+	if f.DateFrom != "" {
+		if p.from, err = time.Parse("2006-01-02", f.DateFrom); err != nil {
+			return nil, fmt.Errorf("invalid dateFrom %q (want YYYY-MM-DD)", f.DateFrom)
+		}
+	}
+	if f.DateTo != "" {
+		if p.to, err = time.Parse("2006-01-02", f.DateTo); err != nil {
+			return nil, fmt.Errorf("invalid dateTo %q (want YYYY-MM-DD)", f.DateTo)
+		}
+	}
+	if !p.from.IsZero() && !p.to.IsZero() && p.to.Before(p.from) {
+		return nil, fmt.Errorf("dateTo %s is before dateFrom %s", f.DateTo, f.DateFrom)
+	}
+	if f.Year < 0 {
+		return nil, fmt.Errorf("invalid year %d", f.Year)
+	}
+	switch f.Media {
+	case ArtistMediaBoth, "both", ArtistMediaAudio, ArtistMediaVideo:
+	default:
+		return nil, fmt.Errorf("invalid media %q (must be audio, video or both)", f.Media)
+	}
+	return p, nil
+}
+
+// ValidateArtistFilter reports whether a filter can be used.
+func ValidateArtistFilter(f *api.ArtistFilter) error {
+	if f == nil {
+		return nil
+	}
+	_, err := parseArtistFilter(f)
+	return err
+}
+
+// matches reports whether a release passes the date, year, media and venue criteria.
+// Releases without a known date fail any date or year criteria.
+func (p *parsedArtistFilter) matches(meta *AlbArtResp, videoFormat int) bool {
+	if !p.from.IsZero() || !p.to.IsZero() || p.Year != 0 {
+		date, ok := parsePerformanceDate(meta.PerformanceDateFormatted, meta.PerformanceDate)
+		if !ok {
+			return false
+		}
+		if !p.from.IsZero() && date.Before(p.from) {
+			return false
+		}
+		if !p.to.IsZero() && date.After(p.to) {
+			return false
+		}
+		if p.Year != 0 && date.Year() != p.Year {
+			return false
+		}
+	}
+	switch p.Media {
+	case ArtistMediaAudio:
+		if meta.ContainerTypeStr == "Video" {
+			return false
+		}
+	case ArtistMediaVideo:
+		if getVideoSkuID(meta, videoFormat, "") == 0 {
+			return false
+		}
+	}
+	if p.Venue != "" {
+		venue := strings.ToLower(strings.Join([]string{meta.VenueName, meta.VenueCity, meta.VenueState}, " "))
+		if !strings.Contains(venue, strings.ToLower(p.Venue)) {
+			return false
+		}
+	}
+	return true
+}
+
+// artistOutRoot returns the output root a release lands beneath: the job override,
+// then the artist's configured path, then the main output path.
+func (d *Downloader) artistOutRoot(meta *AlbArtResp, opts DownloadOptions) string {
+	if opts.OutPath != "" {
+		return opts.OutPath
+	}
+	if artist, ok := d.config().GetEffectiveArtistConfig(meta.ArtistID); ok && meta.ArtistID != 0 {
+		return artist.OutPath
+	}
+	return d.config().OutPath
+}
+
+// filterArtistContainers returns the releases of an artist job that pass its filter,
+// along with the number skipped because they're already in the library.
+func (d *Downloader) filterArtistContainers(containers []*AlbArtResp, opts DownloadOptions) ([]*AlbArtResp, int, error) {
+	if opts.ArtistFilter == nil {
+		return containers, 0, nil
+	}
+	filter, err := parseArtistFilter(opts.ArtistFilter)
+	if err != nil {
+		return nil, 0, err
+	}
+	var matched []*AlbArtResp
+	inLibrary := 0
+	for _, c := range containers {
+		if !filter.matches(c, d.videoFormat(opts)) {
+			continue
+		}
+		if filter.OnlyMissing {
+			if jobID, path := d.libraryMatch(c, d.artistOutRoot(c, opts)); jobID != "" || path != "" {
+				inLibrary++
+				continue
+			}
+		}
+		matched = append(matched, c)
+	}
+	return matched, inLibrary, nil
+}
+
+// filterMediaOptions returns the options releases of a filtered artist job download
+// with: audio-only filters skip videos and video-only filters force them.
+func filterMediaOptions(opts DownloadOptions) DownloadOptions {
+	if opts.ArtistFilter == nil {
+		return opts
+	}
+	switch opts.ArtistFilter.Media {
+	case ArtistMediaAudio:
+		opts.SkipVideos = true
+		opts.ForceVideo = false
+	case ArtistMediaVideo:
+		opts.ForceVideo = true
+		opts.SkipVideos = false
+	}
+	return opts
+}
+
+// PreviewArtistFilter reports which releases of an artist a filtered artist job would
+// download, without creating one. outRoot overrides the path checked by onlyMissing.
+func (d *Downloader) PreviewArtistFilter(artistID string, filter *api.ArtistFilter, outRoot string) (*api.ArtistFilterPreview, error) {
+	if err := ValidateArtistFilter(filter); err != nil {
+		return nil, err
+	}
+	containers, err := d.getArtistMeta(artistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata for artist %s: %w", artistID, err)
+	}
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("no containers found for artist %s", artistID)
+	}
+	opts := DownloadOptions{ArtistFilter: filter, OutPath: outRoot}
+	matched, inLibrary, err := d.filterArtistContainers(containers, opts)
+	if err != nil {
+		return nil, err
+	}
+	preview := &api.ArtistFilterPreview{
+		ArtistID:   artistID,
+		ArtistName: containers[0].ArtistName,
+		Total:      len(containers),
+		Matched:    len(matched),
+		InLibrary:  inLibrary,
+		Containers: make([]api.ContainerSummary, 0, len(matched)),
+	}
+	for _, c := range matched {
+		preview.Containers = append(preview.Containers, d.summarizeContainers([]*AlbArtResp{c}, d.artistOutRoot(c, opts))...)
+	}
+	return preview, nil
+}
@@ -0,0 +1,75 @@
+package downloader
+
+import (
+	"testing"
+
+	"nugs-dl/pkg/api"
+)
+
+func TestArtistFilterMatches(t *testing.T) {
+	show := &AlbArtResp{
+		PerformanceDateFormatted: "1977/05/08",
+		VenueName:                "Barton Hall",
+		VenueCity:                "Ithaca",
+		VenueState:               "NY",
+		ContainerTypeStr:         "Show",
+	}
+	video := &AlbArtResp{PerformanceDate: "05/08/1977", ContainerTypeStr: "Video"}
+	undated := &AlbArtResp{VenueName: "Barton Hall"}
+
+	tests := []struct {
+		name   string
+		filter api.ArtistFilter
+		meta   *AlbArtResp
+		want   bool
+	}{
+		{"empty filter", api.ArtistFilter{}, undated, true},
+		{"year", api.ArtistFilter{Year: 1977}, show, true},
+		{"other year", api.ArtistFilter{Year: 1978}, show, false},
+		{"within dates", api.ArtistFilter{DateFrom: "1977-05-01", DateTo: "1977-05-31"}, show, true},
+		{"dates are inclusive", api.ArtistFilter{DateFrom: "1977-05-08", DateTo: "1977-05-08"}, show, true},
+		{"before dateFrom", api.ArtistFilter{DateFrom: "1977-06-01"}, show, false},
+		{"after dateTo", api.ArtistFilter{DateTo: "1977-05-07"}, show, false},
+		{"unformatted date", api.ArtistFilter{Year: 1977}, video, true},
+		{"undated fails date criteria", api.ArtistFilter{Year: 1977}, undated, false},
+		{"venue name", api.ArtistFilter{Venue: "barton"}, show, true},
+		{"venue city and state", api.ArtistFilter{Venue: "ithaca ny"}, show, true},
+		{"other venue", api.ArtistFilter{Venue: "Winterland"}, show, false},
+		{"audio keeps shows", api.ArtistFilter{Media: ArtistMediaAudio}, show, true},
+		{"audio drops videos", api.ArtistFilter{Media: ArtistMediaAudio}, video, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := parseArtistFilter(&tt.filter)
+			if err != nil {
+				t.Fatalf("parseArtistFilter() error = %v", err)
+			}
+			if got := p.matches(tt.meta, 0); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateArtistFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  *api.ArtistFilter
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"valid", &api.ArtistFilter{DateFrom: "1977-01-01", DateTo: "1977-12-31", Media: "both"}, false},
+		{"bad dateFrom", &api.ArtistFilter{DateFrom: "1977/01/01"}, true},
+		{"bad dateTo", &api.ArtistFilter{DateTo: "31-12-1977"}, true},
+		{"reversed dates", &api.ArtistFilter{DateFrom: "1977-12-31", DateTo: "1977-01-01"}, true},
+		{"negative year", &api.ArtistFilter{Year: -1}, true},
+		{"unknown media", &api.ArtistFilter{Media: "tape"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateArtistFilter(tt.filter); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateArtistFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package downloader
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"nugs-dl/pkg/api"
+)
+
+// catalogCacheTTL is how long an artist's container list is reused while browsing.
+const catalogCacheTTL = 15 * time.Minute
+
+// Catalog sort keys.
+const (
+	CatalogSortDate  = "date"
+	CatalogSortTitle = "title"
+	CatalogSortVenue = "venue"
+)
+
+// catalogEntry is a cached artist container list.
+type catalogEntry struct {
+	fetchedAt  time.Time
+	containers []*AlbArtResp
+}
+
+// CatalogQuery pages, sorts and searches an artist's catalog.
+type CatalogQuery struct {
+	Page     int // 1-based
+	PageSize int
+	Sort     string // date, title or venue
+	Desc     bool
+	Search   string // Case-insensitive substring of title, venue or date
+	Refresh  bool   // Fetch the container list again instead of using the cache
+}
+
+// ReleaseURL returns the play URL of a release.
+func ReleaseURL(containerID int) string {
+	return fmt.Sprintf("https://play.nugs.net/release/%d", containerID)
+}
+
+// IsValidCatalogSort reports whether key is a known catalog sort key.
+func IsValidCatalogSort(key string) bool {
+	switch key {
+	case "", CatalogSortDate, CatalogSortTitle, CatalogSortVenue:
+		return true
+	}
+	return false
+}
+
+// artistContainers returns an artist's containers, cached for catalogCacheTTL.
+func (d *Downloader) artistContainers(artistID string, refresh bool) ([]*AlbArtResp, error) {
+	if !refresh {
+		d.catalogCacheMu.Lock()
+		cached, ok := d.catalogCache[artistID]
+		d.catalogCacheMu.Unlock()
+		if ok && time.Since(cached.fetchedAt) < catalogCacheTTL {
+			return cached.containers, nil
+		}
+	}
+	containers, err := d.getArtistMeta(artistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata for artist %s: %w", artistID, err)
+	}
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("no containers found for artist %s", artistID)
+	}
+	d.catalogCacheMu.Lock()
+	if d.catalogCache == nil {
+		d.catalogCache = make(map[string]*catalogEntry)
+	}
+	d.catalogCache[artistID] = &catalogEntry{fetchedAt: time.Now(), containers: containers}
+	d.catalogCacheMu.Unlock()
+	return containers, nil
+}
+
+// activeContainerJobs maps container IDs to the queued, scheduled or running jobs that
+// will download them. Jobs that haven't started yet are matched by their release URL.
+func (d *Downloader) activeContainerJobs() map[int]string {
+	active := make(map[int]string)
+	for _, job := range d.QueueMgr.GetAllJobs() {
+		if job.Status != api.StatusQueued && job.Status != api.StatusScheduled && job.Status != api.StatusProcessing {
+			continue
+		}
+		id := job.ContainerID
+		if id == "" {
+			if urlID, urlType := CheckUrl(job.OriginalUrl); urlType == ReleaseUrl || urlType == WatchReleaseUrl {
+				id = urlID
+			}
+		}
+		if containerID, err := strconv.Atoi(id); err == nil {
+			active[containerID] = job.ID
+		}
+	}
+	return active
+}
+
+// ArtistCatalog returns a page of an artist's releases with the library state of each:
+// owned, queued or not owned.
+func (d *Downloader) ArtistCatalog(artistID string, q CatalogQuery) (*api.ArtistCatalog, error) {
+	containers, err := d.artistContainers(artistID, q.Refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	search := strings.ToLower(strings.TrimSpace(q.Search))
+	matched := make([]*AlbArtResp, 0, len(containers))
+	for _, c := range containers {
+		if search != "" {
+			text := strings.ToLower(strings.Join([]string{c.ContainerInfo, venueLine(c), newAlbumTemplateData(c).Date}, " "))
+			if !strings.Contains(text, search) {
+				continue
+			}
+		}
+		matched = append(matched, c)
+	}
+
+	key := func(c *AlbArtResp) string {
+		switch q.Sort {
+		case CatalogSortTitle:
+			return strings.ToLower(strings.TrimSpace(c.ContainerInfo))
+		case CatalogSortVenue:
+			return strings.ToLower(venueLine(c))
+		}
+		return newAlbumTemplateData(c).Date
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if q.Desc {
+			return key(matched[i]) > key(matched[j])
+		}
+		return key(matched[i]) < key(matched[j])
+	})
+
+	if q.PageSize <= 0 {
+		q.PageSize = 50
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	catalog := &api.ArtistCatalog{
+		ArtistID:   artistID,
+		ArtistName: containers[0].ArtistName,
+		Total:      len(matched),
+		Page:       q.Page,
+		PageSize:   q.PageSize,
+		Items:      []api.CatalogItem{},
+	}
+	start := (q.Page - 1) * q.PageSize
+	if start >= len(matched) {
+		return catalog, nil
+	}
+	end := start + q.PageSize
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	active := d.activeContainerJobs()
+	for _, c := range matched[start:end] {
+		item := api.CatalogItem{
+			ContainerID: c.ContainerID,
+			Title:       strings.TrimRight(c.ContainerInfo, " "),
+			Date:        newAlbumTemplateData(c).Date,
+			Venue:       venueLine(c),
+			Type:        c.ContainerTypeStr,
+			ArtworkURL:  extractArtworkUrl(c),
+			HasVideo:    getVideoSkuID(c, d.config().VideoFormat, "") != 0,
+			State:       api.CatalogNotOwned,
+		}
+		if jobID, path := d.libraryMatch(c, d.artistOutRoot(c, DownloadOptions{})); jobID != "" || path != "" {
+			item.State = api.CatalogOwned
+			item.JobID = jobID
+		} else if jobID, ok := active[c.ContainerID]; ok {
+			item.State = api.CatalogQueued
+			item.JobID = jobID
+		}
+		catalog.Items = append(catalog.Items, item)
+	}
+	return catalog, nil
+}
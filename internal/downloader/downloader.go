@@ -9,6 +9,8 @@ import (
 	"nugs-dl/internal/logger" // Import the logger package
 	"nugs-dl/internal/queue"
 	"nugs-dl/pkg/api"
+	"sync"
+	"time"
 )
 
 // ErrDuplicateCompleted is returned when a download is attempted for content
@@ -17,26 +19,144 @@ var ErrDuplicateCompleted = errors.New("content already downloaded in a complete
 
 // Downloader handles the core logic for fetching and downloading.
 type Downloader struct {
-	Config       *appConfig.AppConfig      // Holds the application configuration
-	HTTPClient   *http.Client              // Shared HTTP client (with cookie jar)
-	ProgressChan chan<- api.ProgressUpdate // Channel to send progress updates
-	QueueMgr     *queue.QueueManager       // Added QueueManager reference
+	config       func() *appConfig.AppConfig // Returns the current configuration
+	HTTPClient   *http.Client                // Shared HTTP client (with cookie jar)
+	ProgressChan chan<- api.ProgressUpdate   // Channel to send progress updates
+	QueueMgr     *queue.QueueManager         // Added QueueManager reference
+	Notifier     Notifier                    // Optional receiver of notable events (e.g. live recording started)
 	// TODO: Add fields for progress reporting callbacks/channels
+
+	formatCacheMu sync.Mutex
+	formatCache   map[string]*api.FormatReport // Format probe results per container ID
+
+	catalogCacheMu sync.Mutex
+	catalogCache   map[string]*catalogEntry // Container lists per artist ID, for catalog browsing
+
+	gapCacheMu sync.Mutex
+	trackCache map[int]*trackEntry       // Track lists per container ID, for gap reports
+	gapReports map[string]*api.GapReport // Last gap report per artist ID
+
+	sessionMu sync.Mutex
+	session   *session // Last login, reused by format probes and previews
+}
+
+// Notifier receives notable events raised while a job runs.
+type Notifier interface {
+	Notify(title, message string)
+}
+
+// notify logs an event and passes it to the notifier, if one is set and the job's
+// artist hasn't turned notifications off. It is sent in the background, so a slow
+// notification server doesn't hold up the download.
+func (d *Downloader) notify(jobID, title, message string) {
+	logger.Info("[Downloader] "+title, "message", message, "jobID", jobID)
+	if d.Notifier == nil {
+		return
+	}
+	if job, ok := d.QueueMgr.GetJob(jobID); ok && job.Effective != nil && !job.Effective.Notifications {
+		return
+	}
+	go d.Notifier.Notify(title, message)
 }
 
 // DownloadOptions specifies options for a specific download operation.
 // This will replace direct reliance on the global Config struct from main.go
 type DownloadOptions struct {
-	ForceVideo   bool
-	SkipVideos   bool
-	SkipChapters bool
+	ForceVideo    bool
+	SkipVideos    bool
+	SkipChapters  bool
+	Layout        string              // Track layout mode; empty uses the configured layout
+	Selection     *api.TrackSelection // Tracks to download within a release; nil means all non-excluded
+	Formats       []int               // Ordered format preference; empty uses config/trackFallback
+	StrictQuality bool                // Fail tracks instead of falling back outside the preference chain
+	ExtraFormats  []int               // Additional exact formats, each downloaded into its own folder
+	ReplaceLossy  bool                // Replace existing lossy copies of tracks with the lossless download
+	SingleFile    string              // Single-file mode; empty uses the configured mode
+	SplitChapters bool                // Cut video audio into per-song tracks at chapters
+	SplitClips    bool                // Cut videos into per-song MP4 clips at chapters
+	ExtractAudio  bool                // Also write the full audio of videos into the audio library
+	LiveWaitUntil time.Time           // Retry a livestream that isn't up yet until then (scheduled captures)
+	DryRun        bool                // Record a plan of the files instead of writing media
+	Format        int                 // Overrides the configured audio format when set
+	VideoFormat   int                 // Overrides the configured video format when set
+	OutPath       string              // Overrides the configured output root (audio and video) when set
+	ArtistFilter  *api.ArtistFilter   // Releases of an artist job to download; nil means all
 	// We might need specific format overrides here too if the API allows
 }
 
+// outPath returns the output root for audio, honouring the job override.
+func (d *Downloader) outPath(opts DownloadOptions) string {
+	if opts.OutPath != "" {
+		return opts.OutPath
+	}
+	return d.config().OutPath
+}
+
+// videoOutPath returns the output root for videos: the job override, then the
+// configured live video path, then the main output path.
+func (d *Downloader) videoOutPath(opts DownloadOptions) string {
+	if opts.OutPath != "" {
+		return opts.OutPath
+	}
+	if d.config().LiveVideoPath != "" {
+		return d.config().LiveVideoPath
+	}
+	return d.config().OutPath
+}
+
+// videoFormat returns the video format, honouring the job override.
+func (d *Downloader) videoFormat(opts DownloadOptions) int {
+	if opts.VideoFormat != 0 {
+		return opts.VideoFormat
+	}
+	return d.config().VideoFormat
+}
+
+// applyArtistOverrides fills the format, video format and output path a job didn't set
+// from the overrides of the container's artist, then records the settings the job
+// downloads with. Job options win over artist overrides, which win over the globals.
+func (d *Downloader) applyArtistOverrides(jobID string, meta *AlbArtResp, opts *DownloadOptions) {
+	cfg := d.config()
+	settings := &api.EffectiveSettings{ArtistID: meta.ArtistID, ArtistName: meta.ArtistName, Notifications: cfg.Notifications}
+	if artist, ok := cfg.GetEffectiveArtistConfig(meta.ArtistID); ok && meta.ArtistID != 0 {
+		settings.ArtistOverride = true
+		// Only values the artist sets are applied, so formatFallback and liveVideoPath still
+		// apply otherwise; a set value is used even if it equals the global one
+		raw := artistEntry(cfg, meta.ArtistID)
+		if opts.Format == 0 && raw.Format != 0 {
+			opts.Format = raw.Format
+		}
+		if opts.VideoFormat == 0 {
+			opts.VideoFormat = artist.VideoFormat
+		}
+		if opts.OutPath == "" && raw.OutPath != "" {
+			opts.OutPath = raw.OutPath
+		}
+		settings.Notifications = *artist.Notifications
+		logger.Info("Applying artist overrides", "artistID", meta.ArtistID, "artist", meta.ArtistName, "format", opts.Format, "videoFormat", opts.VideoFormat, "outPath", opts.OutPath, "jobID", jobID)
+	}
+	settings.Formats = d.qualityChain(*opts)
+	settings.VideoFormat = d.videoFormat(*opts)
+	settings.OutPath = d.outPath(*opts)
+	d.QueueMgr.SetJobEffectiveSettings(jobID, settings)
+}
+
+// artistEntry returns an artist's entry as written in the config, without the globals
+// merged in, so values the artist sets can be told from inherited ones.
+func artistEntry(cfg *appConfig.AppConfig, artistID int) appConfig.ArtistConfig {
+	for _, a := range cfg.Artists {
+		if a.ID == artistID {
+			return a
+		}
+	}
+	return appConfig.ArtistConfig{}
+}
+
 // NewDownloader creates a new Downloader instance.
-func NewDownloader(cfg *appConfig.AppConfig, client *http.Client, progressChan chan<- api.ProgressUpdate, qm *queue.QueueManager) *Downloader {
+// config is read on every use, so settings saved at runtime apply to the next job.
+func NewDownloader(config func() *appConfig.AppConfig, client *http.Client, progressChan chan<- api.ProgressUpdate, qm *queue.QueueManager) *Downloader {
 	return &Downloader{
-		Config:       cfg,
+		config:       config,
 		HTTPClient:   client,
 		ProgressChan: progressChan, // Store the channel
 		QueueMgr:     qm,           // Store queue manager
@@ -48,62 +168,14 @@ func NewDownloader(cfg *appConfig.AppConfig, client *http.Client, progressChan c
 func (d *Downloader) Download(job *api.DownloadJob) error {
 	logger.Info("[Downloader] Starting job", "jobID", job.ID, "url", job.OriginalUrl, "options", job.Options)
 
-	var (
-		token        string
-		userID       string
-		legacyToken  string
-		legacyUguid  string // Needed for purchased items
-		streamParams *StreamParams
-		err          error
-	)
-
-	// --- Authentication (Uses d.Config) ---
-	if d.Config.Token != "" {
-		token = d.Config.Token // Use provided token
-		logger.Info("Using provided auth token from config.")
-	} else if d.Config.Email != "" && d.Config.Password != "" {
-		// Authenticate with email/password
-		token, err = d.Authenticate(d.Config.Email, d.Config.Password)
-		if err != nil {
-			logger.Error("Authentication failed using email/password", "error", err)
-			return fmt.Errorf("authentication failed: %w", err)
-		}
-		logger.Info("Successfully authenticated using email/password.")
-	} else {
-		logger.Error("Authentication required: email/password or token missing in config")
-		return errors.New("authentication required: provide email/password or token in config")
-	}
-
-	// --- Get User Info & Subscription Details (Needed for StreamParams) ---
-	// Use token (either provided or obtained via login)
-	logger.Info("Fetching user and subscription info...")
-	userID, err = d.GetUserInfo(token)
+	sess, err := d.newSession()
 	if err != nil {
-		logger.Error("Failed to get user info", "error", err)
-		return fmt.Errorf("failed to get user info: %w", err)
-	}
-	subInfo, err := d.GetSubInfo(token)
-	if err != nil {
-		logger.Error("Failed to get subscription info", "error", err)
-		return fmt.Errorf("failed to get subscription info: %w", err)
-	}
-
-	planDesc, _ := getPlan(subInfo) // Use internal getPlan
-	logger.Info("User subscription plan determined", "plan", planDesc)
-
-	// --- Extract Legacy Tokens (Needed for some playlist types AND purchased URLs) ---
-	legacyToken, legacyUguid, err = ExtractLegacyTokens(token)
-	if err != nil {
-		logger.Warn("Could not extract legacy tokens from main auth token", "error", err)
-	}
-
-	// --- Parse Stream Params (Needed for most downloads) ---
-	streamParams, err = ParseStreamParams(userID, subInfo)
-	if err != nil {
-		logger.Error("Failed to parse stream parameters", "error", err)
-		// This is likely fatal for most downloads
-		return fmt.Errorf("failed to parse stream parameters: %w", err)
+		return err
 	}
+	d.sessionMu.Lock()
+	d.session = sess // Fresh login, reused by later format probes and previews
+	d.sessionMu.Unlock()
+	legacyToken, legacyUguid, streamParams := sess.LegacyToken, sess.LegacyUguid, sess.StreamParams
 
 	// --- Process the Single URL ---
 	rawUrl := job.OriginalUrl
@@ -112,9 +184,31 @@ func (d *Downloader) Download(job *api.DownloadJob) error {
 	// Convert job options from api.DownloadOptions to downloader.DownloadOptions
 	// (They are currently identical, but this makes dependencies clearer)
 	dlOpts := DownloadOptions{
-		ForceVideo:   job.Options.ForceVideo,
-		SkipVideos:   job.Options.SkipVideos,
-		SkipChapters: job.Options.SkipChapters,
+		ForceVideo:    job.Options.ForceVideo,
+		SkipVideos:    job.Options.SkipVideos,
+		SkipChapters:  job.Options.SkipChapters,
+		Layout:        job.Options.Layout,
+		Selection:     job.Options.Selection,
+		Formats:       job.Options.Formats,
+		ExtraFormats:  job.Options.ExtraFormats,
+		ReplaceLossy:  job.Options.ReplaceLossy,
+		SingleFile:    job.Options.SingleFile,
+		SplitChapters: job.Options.SplitChapters,
+		SplitClips:    job.Options.SplitClips,
+		ExtractAudio:  job.Options.ExtractAudio,
+		Format:        job.Options.Format,
+		VideoFormat:   job.Options.VideoFormat,
+		OutPath:       job.Options.OutPath,
+		ArtistFilter:  job.Options.ArtistFilter,
+	}
+	dlOpts.DryRun = job.Options.DryRun || d.config().DryRun
+	if dlOpts.DryRun {
+		d.QueueMgr.MarkJobDryRun(job.ID)
+		logger.Info("Dry run: planning downloads without writing media", "jobID", job.ID)
+	}
+	dlOpts.StrictQuality = d.config().StrictQuality
+	if job.Options.StrictQuality != nil {
+		dlOpts.StrictQuality = *job.Options.StrictQuality
 	}
 
 	id, urlType := CheckUrl(rawUrl) // Use CheckUrl from utils.go
@@ -125,7 +219,7 @@ func (d *Downloader) Download(job *api.DownloadJob) error {
 		err = d.processAlbum(job.ID, id, dlOpts, streamParams, nil) // Pass job.ID
 	case UserPlaylistHashUrl, UserPlaylistLibUrl:
 		logger.Info("URL Type: User Playlist", "id", id, "jobID", job.ID)
-		err = d.processPlaylist(job.ID, id, legacyToken, false, streamParams) // Pass job.ID
+		err = d.processPlaylist(job.ID, id, legacyToken, false, dlOpts, streamParams) // Pass job.ID
 	case CatalogPlaylistUrl:
 		logger.Info("URL Type: Catalog Playlist (Short URL)", "originalUrl", rawUrl, "jobID", job.ID)
 		resolvedUrl, resolveErr := d.resolveRedirectURL(rawUrl)
@@ -136,7 +230,7 @@ func (d *Downloader) Download(job *api.DownloadJob) error {
 			resolvedId, resolvedType := CheckUrl(resolvedUrl)
 			if resolvedType == UserPlaylistHashUrl || resolvedType == UserPlaylistLibUrl {
 				logger.Info("Processing resolved playlist ID", "resolvedID", resolvedId, "jobID", job.ID)
-				err = d.processPlaylist(job.ID, resolvedId, legacyToken, true, streamParams) // Pass job.ID
+				err = d.processPlaylist(job.ID, resolvedId, legacyToken, true, dlOpts, streamParams) // Pass job.ID
 			} else {
 				logger.Error("Resolved URL is not a recognized playlist type", "resolvedUrl", resolvedUrl, "type", resolvedType, "jobID", job.ID)
 				err = fmt.Errorf("resolved URL %s is not a recognized playlist type (type %d)", resolvedUrl, resolvedType)
@@ -150,6 +244,15 @@ func (d *Downloader) Download(job *api.DownloadJob) error {
 		err = d.processArtist(job.ID, id, dlOpts, streamParams) // Pass job.ID
 	case ExclusiveLivestreamUrl, WatchExclusiveLivestreamUrl, MyWebcastLibUrl, WatchReleaseUrl:
 		logger.Info("URL Type: Livestream/Webcast/WatchRelease (Container ID)", "id", id, "urlType", urlType, "jobID", job.ID)
+		if (urlType == ExclusiveLivestreamUrl || urlType == WatchExclusiveLivestreamUrl) && !dlOpts.DryRun {
+			// Upcoming livestreams wait as scheduled jobs until shortly before the show
+			if err = d.scheduleLivestream(job.ID, id, &dlOpts); err != nil {
+				if errors.Is(err, ErrScheduled) {
+					logger.Info("Livestream job scheduled", "jobID", job.ID, "detail", err.Error())
+				}
+				return err
+			}
+		}
 		err = d.processAlbum(job.ID, id, dlOpts, streamParams, nil) // Pass job.ID
 	case MyWebcastHashUrl:
 		logger.Info("URL Type: Livestream/Webcast (Show ID)", "id", id, "jobID", job.ID)
@@ -1,12 +1,16 @@
 package downloader
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,16 +28,53 @@ const (
 // getFfmpegCmd determines the correct path/command for ffmpeg based on config.
 // TODO: Needs a reliable way to get script/binary dir if not using PATH.
 func (d *Downloader) getFfmpegCmd() string {
-	if d.Config.UseFfmpegEnvVar {
+	if d.config().UseFfmpegEnvVar {
 		return "ffmpeg"
 	}
 	// Placeholder - assumes ffmpeg is in PATH even if config says otherwise
 	// Needs fix when refactoring how script dir is found.
-	logger.Warn("Cannot determine relative ffmpeg path, assuming ffmpeg is in system PATH.", "useFfmpegEnvVar", d.Config.UseFfmpegEnvVar)
+	logger.Warn("Cannot determine relative ffmpeg path, assuming ffmpeg is in system PATH.", "useFfmpegEnvVar", d.config().UseFfmpegEnvVar)
 	return "ffmpeg"
 	// return "./ffmpeg" // Original alternative
 }
 
+// getFfprobeCmd returns the ffprobe command, found alongside ffmpeg.
+func (d *Downloader) getFfprobeCmd() string {
+	ffmpegCmd := d.getFfmpegCmd()
+	return filepath.Join(filepath.Dir(ffmpegCmd), strings.Replace(filepath.Base(ffmpegCmd), "ffmpeg", "ffprobe", 1))
+}
+
+// probeDuration reads the duration of a media file in seconds with sub-second
+// precision, which getDuration's whole seconds lack.
+func (d *Downloader) probeDuration(path string) (float64, error) {
+	var errBuffer bytes.Buffer
+	cmd := exec.Command(d.getFfprobeCmd(), "-v", "error", "-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1", path)
+	cmd.Stderr = &errBuffer
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe duration check failed: %w\nOutput:\n%s", err, errBuffer.String())
+	}
+	dur, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse ffprobe duration '%s': %w", strings.TrimSpace(string(out)), err)
+	}
+	return dur, nil
+}
+
+// probeAudioCodec returns the codec name of a file's first audio stream, e.g. "alac" or "aac".
+func (d *Downloader) probeAudioCodec(path string) (string, error) {
+	var errBuffer bytes.Buffer
+	cmd := exec.Command(d.getFfprobeCmd(), "-v", "error", "-select_streams", "a:0", "-show_entries", "stream=codec_name",
+		"-of", "default=noprint_wrappers=1:nokey=1", path)
+	cmd.Stderr = &errBuffer
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("ffprobe codec check failed: %w\nOutput:\n%s", err, errBuffer.String())
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
 // extractDuration parses ffmpeg's stderr output to find the duration.
 // (Moved from main.go)
 func extractDuration(errStr string) string {
@@ -114,12 +155,24 @@ func getNextChapStart(chapters []interface{}, currentIndex int) float64 {
 	return -1 // Indicate no next chapter or error
 }
 
-// writeChapsFile creates the metadata file used by ffmpeg to embed chapters.
-// (Moved from main.go)
-func writeChapsFile(chapters []interface{}, durationSeconds int) error {
-	f, err := os.OpenFile(chapsFileFname, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+// chapterMark is a single chapter, positioned in seconds.
+type chapterMark struct {
+	Title string
+	Start float64
+	End   float64
+}
+
+// escapeFfmetadata escapes the characters that are special in FFMETADATA values.
+func escapeFfmetadata(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n")
+	return r.Replace(s)
+}
+
+// writeChapterMarks writes an FFMETADATA file with optional global tags and chapters.
+func writeChapterMarks(path string, tags map[string]string, marks []chapterMark) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
 	if err != nil {
-		return fmt.Errorf("failed to create chapter file %s: %w", chapsFileFname, err)
+		return fmt.Errorf("failed to create chapter file %s: %w", path, err)
 	}
 	defer f.Close()
 
@@ -128,7 +181,38 @@ func writeChapsFile(chapters []interface{}, durationSeconds int) error {
 	if err != nil {
 		return fmt.Errorf("failed to write chapter file header: %w", err)
 	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if tags[k] == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(f, "%s=%s\n", k, escapeFfmetadata(tags[k])); err != nil {
+			return fmt.Errorf("failed to write chapter file tag %s: %w", k, err)
+		}
+	}
 
+	for i, m := range marks {
+		// Write chapter block
+		_, err = fmt.Fprintf(f, "\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\nTITLE=%s\n",
+			int64(math.Round(m.Start*1000)),
+			int64(math.Round(m.End*1000)),
+			escapeFfmetadata(m.Title),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to write chapter %d data: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// videoChapterMarks converts the videoChapters metadata into chapter marks.
+// A chapter ends a second before the next one starts; the last ends at durationSeconds.
+func videoChapterMarks(chapters []interface{}, durationSeconds int) []chapterMark {
+	var marks []chapterMark
 	for i, chapter := range chapters {
 		chapterMap, ok := chapter.(map[string]interface{})
 		if !ok {
@@ -159,40 +243,169 @@ func writeChapsFile(chapters []interface{}, durationSeconds int) error {
 		if endRounded > durationSeconds {
 			endRounded = durationSeconds
 		}
+		marks = append(marks, chapterMark{Title: chapterName, Start: float64(startRounded), End: float64(endRounded)})
+	}
+	return marks
+}
 
-		// Write chapter block
-		_, err = fmt.Fprintf(f, "\n[CHAPTER]\nTIMEBASE=1/1\nSTART=%d\nEND=%d\nTITLE=%s\n",
-			startRounded,
-			endRounded,
-			chapterName, // TODO: Escape title if needed?
-		)
-		if err != nil {
-			return fmt.Errorf("failed to write chapter %d data: %w", i, err)
+// chapsFilePath returns the chapter metadata file for a video's TS file. It sits beside
+// the video, so videos processed at the same time (e.g. a live capture) don't clash.
+func chapsFilePath(tsPath string) string {
+	return strings.TrimSuffix(tsPath, filepath.Ext(tsPath)) + "." + chapsFileFname
+}
+
+// writeChapsFile creates the metadata file used by ffmpeg to embed chapters into the
+// video remuxed from tsPath.
+// (Moved from main.go)
+func writeChapsFile(tsPath string, chapters []interface{}, durationSeconds int) error {
+	marks := videoChapterMarks(chapters, durationSeconds)
+	path := chapsFilePath(tsPath)
+	if err := writeChapterMarks(path, nil, marks); err != nil {
+		return err
+	}
+	logger.Info("FFmpeg chapter metadata file created successfully.", "filename", path)
+	return nil
+}
+
+// trackTags builds the metadata tags written to an audio track from its template data.
+func trackTags(data PathTemplateData) map[string]string {
+	tags := map[string]string{
+		"title":        data.SongTitle,
+		"artist":       data.ArtistName,
+		"album_artist": data.ArtistName,
+		"album":        data.ContainerInfo,
+	}
+	if data.PlaylistName != "" {
+		tags["album"] = data.PlaylistName
+	}
+	if data.Date != "" {
+		tags["date"] = data.Date
+	} else if data.Year != "" {
+		tags["date"] = data.Year
+	}
+	if data.TrackNum > 0 {
+		tags["track"] = fmt.Sprintf("%d/%d", data.TrackNum, data.TrackTotal)
+	}
+	if data.DiscNum > 0 {
+		disc := strconv.Itoa(data.DiscNum)
+		if data.DiscTotal > 0 {
+			disc += "/" + strconv.Itoa(data.DiscTotal)
+		}
+		tags["disc"] = disc
+	}
+	return tags
+}
+
+// metadataArgs turns tags into ffmpeg -metadata arguments. Empty values are skipped.
+func metadataArgs(tags map[string]string) []string {
+	// Sort keys so the command line is stable in logs
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var args []string
+	for _, k := range keys {
+		if tags[k] == "" {
+			continue
+		}
+		args = append(args, "-metadata", k+"="+tags[k])
+	}
+	return args
+}
+
+// writeTags rewrites the metadata tags of a media file in place using a stream copy.
+// Empty tag values are skipped.
+func (d *Downloader) writeTags(filePath string, tags map[string]string) error {
+	ext := filepath.Ext(filePath)
+	tmpPath := strings.TrimSuffix(filePath, ext) + ".tagging" + ext
+
+	args := []string{"-hide_banner", "-i", filePath, "-map", "0", "-map_metadata", "0", "-c", "copy"}
+	args = append(args, metadataArgs(tags)...)
+	args = append(args, "-y", tmpPath)
+
+	var errBuffer bytes.Buffer
+	cmd := exec.Command(d.getFfmpegCmd(), args...)
+	cmd.Stderr = &errBuffer
+	logger.Debug("Executing FFmpeg tag command", "arguments", args)
+	if err := cmd.Run(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("ffmpeg tagging failed: %w\nOutput:\n%s", err, errBuffer.String())
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s with tagged copy: %w", filePath, err)
+	}
+	return nil
+}
+
+// runFfmpegWithProgress runs ffmpeg with -progress output on stdout and reports the
+// percentage of durationSecs processed so far through onProgress, at most once per percent.
+func (d *Downloader) runFfmpegWithProgress(args []string, durationSecs int, onProgress func(pct float64)) error {
+	args = append([]string{"-progress", "pipe:1", "-nostats"}, args...)
+	var errBuffer bytes.Buffer
+	cmd := exec.Command(d.getFfmpegCmd(), args...)
+	cmd.Stderr = &errBuffer
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to attach to ffmpeg output: %w", err)
+	}
+	logger.Debug("Executing FFmpeg command with progress", "arguments", args)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	lastPct := -1
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		// Progress is reported as key=value lines; out_time_us (and the misnamed
+		// out_time_ms) hold the output position in microseconds
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok || (key != "out_time_us" && key != "out_time_ms") || durationSecs <= 0 {
+			continue
+		}
+		us, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || us < 0 {
+			continue
+		}
+		pct := float64(us) / 1e6 / float64(durationSecs) * 100.0
+		if pct > 100 {
+			pct = 100
+		}
+		if int(pct) != lastPct {
+			lastPct = int(pct)
+			onProgress(pct)
 		}
 	}
-	logger.Info("FFmpeg chapter metadata file created successfully.", "filename", chapsFileFname)
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %w\nOutput:\n%s", err, errBuffer.String())
+	}
 	return nil
 }
 
 // tsToMp4 remuxes a TS file (downloaded video segments) to an MP4 container,
-// optionally embedding chapter metadata.
+// optionally embedding chapter metadata, and writes the given metadata atoms.
 // (Moved from main.go)
-func (d *Downloader) tsToMp4(tsInputPath, mp4OutputPath string, chaptersAvailable bool) error {
+func (d *Downloader) tsToMp4(tsInputPath, mp4OutputPath string, chaptersAvailable bool, tags map[string]string) error {
 	ffmpegCmd := d.getFfmpegCmd()
 	var errBuffer bytes.Buffer
 	args := []string{"-hide_banner", "-i", tsInputPath}
+	chapsFile := chapsFilePath(tsInputPath)
 
 	if chaptersAvailable {
 		// Check if chapter file exists first
-		if _, err := os.Stat(chapsFileFname); err == nil {
-			args = append(args, "-f", "ffmetadata", "-i", chapsFileFname, "-map_metadata", "1")
+		if _, err := os.Stat(chapsFile); err == nil {
+			args = append(args, "-f", "ffmetadata", "-i", chapsFile, "-map_metadata", "1")
 		} else {
-			logger.Warn("Chapter metadata file not found, skipping chapter embedding.", "expectedFile", chapsFileFname, "inputTS", tsInputPath)
+			logger.Warn("Chapter metadata file not found, skipping chapter embedding.", "expectedFile", chapsFile, "inputTS", tsInputPath)
 			// Reset flag so we don't try to delete it later
 			chaptersAvailable = false
 		}
 	}
-	args = append(args, "-c", "copy", "-y", mp4OutputPath) // Copy streams, overwrite output
+	args = append(args, "-c", "copy")
+	args = append(args, metadataArgs(tags)...)
+	args = append(args, "-y", mp4OutputPath) // Copy streams, overwrite output
 
 	cmd := exec.Command(ffmpegCmd, args...)
 	cmd.Stderr = &errBuffer
@@ -213,9 +426,9 @@ func (d *Downloader) tsToMp4(tsInputPath, mp4OutputPath string, chaptersAvailabl
 	}
 	// Delete the chapter file if it was used
 	if chaptersAvailable {
-		err = os.Remove(chapsFileFname)
+		err = os.Remove(chapsFile)
 		if err != nil {
-			logger.Warn("Failed to delete temporary chapter metadata file.", "file", chapsFileFname, "error", err)
+			logger.Warn("Failed to delete temporary chapter metadata file.", "file", chapsFile, "error", err)
 		}
 	}
 
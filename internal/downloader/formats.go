@@ -0,0 +1,113 @@
+package downloader
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"nugs-dl/internal/logger"
+	"nugs-dl/pkg/api"
+)
+
+// formatCacheTTL is how long format probe results are reused for a container.
+const formatCacheTTL = 24 * time.Hour
+
+// probeFormatName names a probed format; HLS-only audio is told apart from plain AAC.
+func probeFormatName(format int) string {
+	if format == 6 {
+		return "AAC (HLS-only)"
+	}
+	return formatNames[format]
+}
+
+// ProbeFormats reports which audio qualities are offered for each track of a release,
+// with their sizes. Results are cached per container; refresh probes again.
+func (d *Downloader) ProbeFormats(containerID string, refresh bool) (*api.FormatReport, error) {
+	if !refresh {
+		d.formatCacheMu.Lock()
+		cached, ok := d.formatCache[containerID]
+		d.formatCacheMu.Unlock()
+		if ok && time.Since(cached.ProbedAt) < formatCacheTTL {
+			report := *cached
+			report.Cached = true
+			return &report, nil
+		}
+	}
+
+	albumMeta, err := d.getAlbumMeta(containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata for container %s: %w", containerID, err)
+	}
+	if albumMeta.Response == nil {
+		return nil, fmt.Errorf("API returned empty response for container %s", containerID)
+	}
+	meta := albumMeta.Response
+	tracks := meta.Tracks
+	if len(tracks) == 0 {
+		tracks = meta.Songs
+	}
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("release %s has no tracks", containerID)
+	}
+
+	sess, err := d.cachedSession()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &api.FormatReport{
+		ContainerID: meta.ContainerID,
+		ArtistName:  meta.ArtistName,
+		Title:       strings.TrimRight(meta.ContainerInfo, " "),
+		ProbedAt:    time.Now().UTC(),
+		Tracks:      make([]api.TrackFormats, 0, len(tracks)),
+	}
+	summary := make(map[int]*api.FormatSummary)
+	for i := range tracks {
+		track := &tracks[i]
+		tf := api.TrackFormats{Index: i + 1, TrackID: track.TrackID, Title: track.SongTitle, Formats: []api.FormatAvailable{}}
+		quals, err := d.probeTrackQualities("", track, sess.StreamParams)
+		if err != nil {
+			tf.Error = err.Error()
+			report.Tracks = append(report.Tracks, tf)
+			continue
+		}
+		tf.HlsOnly = checkIfHlsOnly(quals)
+		sort.Slice(quals, func(a, b int) bool { return quals[a].Format < quals[b].Format })
+		for _, q := range quals {
+			size := int64(-1)
+			if q.Format != 6 {
+				size = d.contentLength(q.URL)
+			}
+			tf.Formats = append(tf.Formats, api.FormatAvailable{Format: q.Format, FormatName: probeFormatName(q.Format), Specs: q.Specs, SizeBytes: size})
+
+			s, ok := summary[q.Format]
+			if !ok {
+				s = &api.FormatSummary{Format: q.Format, FormatName: probeFormatName(q.Format)}
+				summary[q.Format] = s
+			}
+			s.Tracks++
+			if size > 0 {
+				s.TotalBytes += size
+			}
+		}
+		report.Tracks = append(report.Tracks, tf)
+	}
+	report.Summary = make([]api.FormatSummary, 0, len(summary))
+	for _, s := range summary {
+		report.Summary = append(report.Summary, *s)
+	}
+	sort.Slice(report.Summary, func(a, b int) bool { return report.Summary[a].Format < report.Summary[b].Format })
+
+	d.formatCacheMu.Lock()
+	if d.formatCache == nil {
+		d.formatCache = make(map[string]*api.FormatReport)
+	}
+	d.formatCache[containerID] = report
+	d.formatCacheMu.Unlock()
+	logger.Info("Probed release formats", "containerID", containerID, "tracks", len(report.Tracks), "formats", len(report.Summary))
+
+	result := *report
+	return &result, nil
+}
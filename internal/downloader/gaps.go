@@ -0,0 +1,353 @@
+package downloader
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"nugs-dl/internal/logger"
+	"nugs-dl/pkg/api"
+)
+
+// ownedRelease is what the library holds of a release.
+type ownedRelease struct {
+	jobID, path string
+	format      int            // Format of most tracks; 0 if unknown
+	degraded    bool           // The job fell back from the wanted format
+	tracks      map[string]int // Titles downloaded by the job; nil for a library folder
+	files       []string       // Lower-case audio file names without extension, for a library folder
+	have        int
+}
+
+// leadingTrackNum matches the track number a file name starts with, after an optional
+// disc number, e.g. "03. Song" or "2-03 Song".
+var leadingTrackNum = regexp.MustCompile(`^\s*(?:\d+-)?(\d+)`)
+
+// trackCacheTTL is how long a release's fetched track list is reused for gap reports.
+const trackCacheTTL = 24 * time.Hour
+
+// trackEntry is a cached release track list.
+type trackEntry struct {
+	fetchedAt time.Time
+	tracks    []Track
+}
+
+// releaseTracks returns the tracks of a release, fetching its metadata when the
+// artist listing doesn't include them. Fetched lists are cached for trackCacheTTL;
+// refresh fetches again.
+func (d *Downloader) releaseTracks(meta *AlbArtResp, refresh bool) ([]Track, error) {
+	if len(meta.Tracks) > 0 {
+		return meta.Tracks, nil
+	}
+	if len(meta.Songs) > 0 {
+		return meta.Songs, nil
+	}
+	if !refresh {
+		d.gapCacheMu.Lock()
+		cached, ok := d.trackCache[meta.ContainerID]
+		d.gapCacheMu.Unlock()
+		if ok && time.Since(cached.fetchedAt) < trackCacheTTL {
+			return cached.tracks, nil
+		}
+	}
+	albumMeta, err := d.getAlbumMeta(strconv.Itoa(meta.ContainerID))
+	if err != nil {
+		return nil, err
+	}
+	if albumMeta.Response == nil {
+		return nil, fmt.Errorf("API returned empty response for container %d", meta.ContainerID)
+	}
+	tracks := albumMeta.Response.Tracks
+	if len(tracks) == 0 {
+		tracks = albumMeta.Response.Songs
+	}
+	d.gapCacheMu.Lock()
+	if d.trackCache == nil {
+		d.trackCache = make(map[int]*trackEntry)
+	}
+	d.trackCache[meta.ContainerID] = &trackEntry{fetchedAt: time.Now(), tracks: tracks}
+	d.gapCacheMu.Unlock()
+	return tracks, nil
+}
+
+// ownedFromJob describes a release downloaded by a completed job from its track results.
+func ownedFromJob(job *api.DownloadJob) *ownedRelease {
+	owned := &ownedRelease{jobID: job.ID, path: job.OutputPath, degraded: job.Degraded, tracks: make(map[string]int)}
+	counts := make(map[int]int)
+	for _, t := range job.Tracks {
+		if t.Error != "" || t.Extra {
+			continue
+		}
+		owned.have++
+		owned.tracks[t.Title]++
+		counts[t.Format]++
+	}
+	for format, n := range counts {
+		if n > counts[owned.format] {
+			owned.format = format
+		}
+	}
+	return owned
+}
+
+// ownedFromFolder describes a release found in the library by its audio files. FLAC
+// files are taken as lossless; M4A files may hold ALAC or AAC, so one is probed.
+func (d *Downloader) ownedFromFolder(path string) *ownedRelease {
+	owned := &ownedRelease{path: path}
+	var m4aPath string
+	flac, m4a := 0, 0
+	filepath.WalkDir(path, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || strings.Contains(entry.Name(), singleFileSuffix) {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(p))
+		switch ext {
+		case ".flac":
+			flac++
+		case ".m4a":
+			m4a++
+			if m4aPath == "" {
+				m4aPath = p
+			}
+		default:
+			return nil
+		}
+		owned.files = append(owned.files, strings.ToLower(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))))
+		return nil
+	})
+	owned.have = flac + m4a
+
+	m4aFormat := 0
+	if m4aPath != "" {
+		codec, err := d.probeAudioCodec(m4aPath)
+		switch {
+		case err != nil:
+			logger.Warn("Could not probe library file codec", "path", m4aPath, "error", err)
+		case codec == "alac":
+			m4aFormat = 1
+		case codec == "aac":
+			m4aFormat = 5
+		}
+	}
+	switch {
+	case m4a == 0 && flac > 0:
+		owned.format = 2
+	case m4a > 0 && flac == 0:
+		owned.format = m4aFormat
+	case m4aFormat != 0:
+		// Mixed folder: the format of most files
+		owned.format = 2
+		if m4a > flac {
+			owned.format = m4aFormat
+		}
+	}
+	return owned
+}
+
+// missingTrackIndices returns the 1-based indices of wanted tracks that aren't owned.
+// Job results are matched by title; library files by the track title or the number
+// their name starts with. ok is false when no file could be matched to a track, so
+// which tracks are missing isn't known.
+func missingTrackIndices(tracks []Track, owned *ownedRelease) (indices []string, ok bool) {
+	if owned.tracks != nil {
+		have := make(map[string]int, len(owned.tracks))
+		for title, n := range owned.tracks {
+			have[title] = n
+		}
+		for i, t := range tracks {
+			if t.TrackExclude != 0 {
+				continue
+			}
+			if have[t.SongTitle] > 0 {
+				have[t.SongTitle]--
+				continue
+			}
+			indices = append(indices, strconv.Itoa(i+1))
+		}
+		return indices, true
+	}
+
+	used := make([]bool, len(owned.files))
+	found := make([]bool, len(tracks))
+	matched := false
+	// Titles first, as they are the surer match
+	for i, t := range tracks {
+		title := strings.ToLower(SanitizeFilename(strings.TrimSpace(t.SongTitle)))
+		if t.TrackExclude != 0 || title == "" {
+			continue
+		}
+		for j, f := range owned.files {
+			if !used[j] && strings.Contains(f, title) {
+				used[j], found[i], matched = true, true, true
+				break
+			}
+		}
+	}
+	for i, t := range tracks {
+		if t.TrackExclude != 0 || found[i] {
+			continue
+		}
+		for j, f := range owned.files {
+			if m := leadingTrackNum.FindStringSubmatch(f); !used[j] && m != nil && m[1] != "" {
+				if n, err := strconv.Atoi(m[1]); err == nil && n == i+1 {
+					used[j], found[i], matched = true, true, true
+					break
+				}
+			}
+		}
+	}
+	if !matched {
+		return nil, false
+	}
+	for i, t := range tracks {
+		if t.TrackExclude == 0 && !found[i] {
+			indices = append(indices, strconv.Itoa(i+1))
+		}
+	}
+	return indices, true
+}
+
+// wantedFormat returns the format an artist's releases should be owned in: the
+// artist's override, then the head of the configured preference chain.
+func (d *Downloader) wantedFormat(meta *AlbArtResp) int {
+	if meta.ArtistID != 0 {
+		if raw := artistEntry(d.config(), meta.ArtistID); raw.Format != 0 {
+			return raw.Format
+		}
+	}
+	return d.qualityChain(DownloadOptions{})[0]
+}
+
+// ArtistGaps compares an artist's catalog with the library and download history and
+// lists releases that are missing, owned only in a lower quality, or missing tracks.
+// Track lists are only fetched for releases found in the library.
+func (d *Downloader) ArtistGaps(artistID string, refresh bool) (*api.GapReport, error) {
+	containers, err := d.artistContainers(artistID, refresh)
+	if err != nil {
+		return nil, err
+	}
+	wanted := d.wantedFormat(containers[0])
+	report := &api.GapReport{
+		ArtistID:     artistID,
+		ArtistName:   containers[0].ArtistName,
+		GeneratedAt:  time.Now().UTC(),
+		WantedFormat: formatNames[wanted],
+		Total:        len(containers),
+		Items:        []api.GapItem{},
+	}
+
+	for _, c := range containers {
+		data := newAlbumTemplateData(c)
+		item := api.GapItem{
+			ContainerID: c.ContainerID,
+			Title:       data.ContainerInfo,
+			Date:        data.Date,
+			Venue:       venueLine(c),
+			Url:         ReleaseURL(c.ContainerID),
+		}
+		jobID, path := d.libraryMatch(c, d.artistOutRoot(c, DownloadOptions{}))
+		if jobID == "" && path == "" {
+			item.Gaps = []string{api.GapMissing}
+			report.Missing++
+			report.Items = append(report.Items, item)
+			continue
+		}
+		report.Owned++
+
+		var owned *ownedRelease
+		if job, ok := d.QueueMgr.GetJob(jobID); ok {
+			owned = ownedFromJob(job)
+		} else {
+			owned = d.ownedFromFolder(path)
+		}
+		item.JobID = owned.jobID
+		item.Path = owned.path
+		if owned.format != 0 {
+			item.FormatName = formatNames[owned.format]
+		}
+
+		if owned.degraded || (owned.format != 0 && isLossyFormat(owned.format) && !isLossyFormat(wanted)) {
+			item.Gaps = append(item.Gaps, api.GapLowerQuality)
+			report.LowerQuality++
+		}
+
+		tracks, err := d.releaseTracks(c, refresh)
+		if err != nil {
+			logger.Warn("Could not get tracks to check a release for gaps", "containerID", c.ContainerID, "error", err)
+		}
+		for _, t := range tracks {
+			if t.TrackExclude == 0 {
+				item.ExpectedTracks++
+			}
+		}
+		item.HaveTracks = owned.have
+		if item.ExpectedTracks > 0 && owned.have < item.ExpectedTracks {
+			item.Gaps = append(item.Gaps, api.GapMissingTracks)
+			var known bool
+			item.MissingTracks, known = missingTrackIndices(tracks, owned)
+			item.MissingTracksUnknown = !known
+			report.Incomplete++
+		}
+		if len(item.Gaps) > 0 {
+			report.Items = append(report.Items, item)
+		}
+	}
+	logger.Info("Built collection gap report", "artistID", artistID, "total", report.Total, "owned", report.Owned, "missing", report.Missing, "lowerQuality", report.LowerQuality, "incomplete", report.Incomplete)
+
+	d.gapCacheMu.Lock()
+	if d.gapReports == nil {
+		d.gapReports = make(map[string]*api.GapReport)
+	}
+	d.gapReports[artistID] = report
+	d.gapCacheMu.Unlock()
+	return report, nil
+}
+
+// LastArtistGaps returns the last gap report built for an artist if it is younger
+// than catalogCacheTTL, and builds a new one otherwise.
+func (d *Downloader) LastArtistGaps(artistID string) (*api.GapReport, error) {
+	d.gapCacheMu.Lock()
+	report, ok := d.gapReports[artistID]
+	d.gapCacheMu.Unlock()
+	if ok && time.Since(report.GeneratedAt) < catalogCacheTTL {
+		return report, nil
+	}
+	return d.ArtistGaps(artistID, false)
+}
+
+// GapJobOptions returns the options of a job that fills the given gaps of a release.
+// Owned releases are fetched again through a track selection, which lets the job past
+// the check for releases that were already downloaded; existing files are skipped,
+// except lossy ones when upgrading a release owned in a lower quality.
+func GapJobOptions(item api.GapItem, gaps map[string]bool, opts api.DownloadOptions) (api.DownloadOptions, bool) {
+	for _, g := range item.Gaps {
+		if !gaps[g] {
+			continue
+		}
+		switch g {
+		case api.GapMissing:
+			return opts, true
+		case api.GapLowerQuality:
+			opts.Selection = &api.TrackSelection{Titles: []string{"/.*/"}} // Every track
+			// ALAC and AAC share a file name, and AAC copies shouldn't linger next to FLAC
+			opts.ReplaceLossy = true
+			if opts.StrictQuality == nil {
+				strict := true // Don't settle for the lossy format again
+				opts.StrictQuality = &strict
+			}
+			return opts, true
+		case api.GapMissingTracks:
+			if item.MissingTracksUnknown || len(item.MissingTracks) == 0 {
+				opts.Selection = &api.TrackSelection{Titles: []string{"/.*/"}} // Every track; owned ones are skipped
+				return opts, true
+			}
+			opts.Selection = &api.TrackSelection{Tracks: item.MissingTracks}
+			return opts, true
+		}
+	}
+	return opts, false
+}
@@ -0,0 +1,44 @@
+package downloader
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMissingTrackIndices(t *testing.T) {
+	tracks := []Track{
+		{SongTitle: "Dark Star"},
+		{SongTitle: "St. Stephen"},
+		{SongTitle: "Tuning", TrackExclude: 1},
+		{SongTitle: "Help/Slipknot!"},
+		{SongTitle: "Lovelight"},
+	}
+	tests := []struct {
+		name   string
+		tracks []Track
+		owned  *ownedRelease
+		want   []string
+		wantOK bool
+	}{
+		{"job titles", tracks, &ownedRelease{tracks: map[string]int{"Dark Star": 1, "Lovelight": 1}}, []string{"2", "4"}, true},
+		{"job complete", tracks, &ownedRelease{tracks: map[string]int{"Dark Star": 1, "St. Stephen": 1, "Help/Slipknot!": 1, "Lovelight": 1}}, nil, true},
+		{"job repeated titles", []Track{{SongTitle: "Jam"}, {SongTitle: "Drums"}, {SongTitle: "Jam"}}, &ownedRelease{tracks: map[string]int{"Jam": 1, "Drums": 1}}, []string{"3"}, true},
+		{"folder titles", tracks, &ownedRelease{files: []string{"01. dark star", "05 lovelight"}}, []string{"2", "4"}, true},
+		{"folder sanitized title", tracks, &ownedRelease{files: []string{"track help_slipknot!"}}, []string{"1", "2", "5"}, true},
+		{"folder leading numbers", tracks, &ownedRelease{files: []string{"01 untitled", "02-untitled"}}, []string{"4", "5"}, true},
+		{"folder disc-prefixed numbers", tracks, &ownedRelease{files: []string{"1-05 track"}}, []string{"1", "2", "4"}, true},
+		{"titles before numbers", tracks, &ownedRelease{files: []string{"02 dark star", "01 something"}}, []string{"2", "4", "5"}, true},
+		{"nothing matched", tracks, &ownedRelease{files: []string{"cover", "notes"}}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := missingTrackIndices(tt.tracks, tt.owned)
+			if ok != tt.wantOK {
+				t.Fatalf("missingTrackIndices() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("missingTrackIndices() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -323,10 +323,8 @@ func (d *Downloader) downloadHls(jobID, trackPath, masterPlaylistUrl string) err
 
 // --- HLS Video specific functions (To be moved/merged with ffmpeg/video logic) ---
 
-// getSegUrls parses a media playlist to get all segment URIs.
-// (Moved from main.go, used by video download)
-func (d *Downloader) getSegUrls(mediaPlaylistUrl, query string) ([]string, error) {
-	var segUrls []string
+// fetchMediaPlaylist downloads and decodes an HLS media playlist.
+func (d *Downloader) fetchMediaPlaylist(mediaPlaylistUrl string) (*m3u8.MediaPlaylist, error) {
 	req, err := d.HTTPClient.Get(mediaPlaylistUrl)
 	if err != nil {
 		return nil, fmt.Errorf("failed to GET HLS media playlist %s: %w", mediaPlaylistUrl, err)
@@ -343,16 +341,17 @@ func (d *Downloader) getSegUrls(mediaPlaylistUrl, query string) ([]string, error
 	if listType != m3u8.MEDIA {
 		return nil, fmt.Errorf("expected HLS media playlist but got master for %s", mediaPlaylistUrl)
 	}
+	return playlist.(*m3u8.MediaPlaylist), nil
+}
 
-	media := playlist.(*m3u8.MediaPlaylist)
+// mediaSegUrls lists the segment URIs of a media playlist with query appended.
+func mediaSegUrls(media *m3u8.MediaPlaylist, query string) []string {
+	var segUrls []string
 	for _, seg := range media.Segments {
 		if seg == nil {
 			break
 		}
 		segUrls = append(segUrls, seg.URI+query)
 	}
-	if len(segUrls) == 0 {
-		return nil, errors.New("HLS media playlist contained no segments")
-	}
-	return segUrls, nil
+	return segUrls
 }
@@ -0,0 +1,135 @@
+package downloader
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Track layout modes. They control track numbering, disc subfolders and the disc/track tags.
+const (
+	LayoutSequential  = "sequential"   // 01..N across the whole show (default)
+	LayoutDiscFolders = "disc-folders" // "Disc N" subfolders, numbering restarts per disc
+	LayoutDiscTrack   = "disc-track"   // dNtNN numbering, numbering restarts per disc
+	LayoutSetPrefixed = "set-prefixed" // sNtNN numbering, numbering restarts per set
+)
+
+// ValidLayouts lists the accepted track layout modes.
+var ValidLayouts = []string{LayoutSequential, LayoutDiscFolders, LayoutDiscTrack, LayoutSetPrefixed}
+
+// trackSlot describes where a track lands within the chosen layout.
+type trackSlot struct {
+	TrackNum   int    // Track number written to the filename and track tag
+	TrackTotal int    // Number of tracks in the same disc/set (or the whole show)
+	DiscNum    int    // Disc number written to the disc tag
+	DiscTotal  int    // Number of discs (sets for set-prefixed)
+	SetNum     int    // Set number from metadata
+	Number     string // Formatted number, e.g. "03", "d2t03", "s2t03"
+	SubDir     string // Subfolder beneath the album folder, if any
+}
+
+// IsValidLayout reports whether mode is a known layout (empty means default).
+func IsValidLayout(mode string) bool {
+	if mode == "" {
+		return true
+	}
+	for _, l := range ValidLayouts {
+		if l == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// effectiveLayout picks the job layout, then the configured one, then the default.
+func (d *Downloader) effectiveLayout(opts DownloadOptions) string {
+	if opts.Layout != "" {
+		return opts.Layout
+	}
+	if d.config().TrackLayout != "" {
+		return d.config().TrackLayout
+	}
+	return LayoutSequential
+}
+
+// groupNum returns the disc or set number a track belongs to for the layout.
+// Missing numbers (0) are treated as 1.
+func groupNum(track *Track, mode string) int {
+	n := track.DiscNum
+	if mode == LayoutSetPrefixed {
+		n = track.SetNum
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// layoutTracks assigns a slot to each track according to the layout mode.
+// Tracks keep their metadata order; numbering restarts for each disc or set.
+func layoutTracks(tracks []Track, mode string) []trackSlot {
+	slots := make([]trackSlot, len(tracks))
+	if len(tracks) == 0 {
+		return slots
+	}
+	if mode == "" || mode == LayoutSequential {
+		for i := range tracks {
+			slots[i] = trackSlot{
+				TrackNum:   i + 1,
+				TrackTotal: len(tracks),
+				DiscNum:    1,
+				DiscTotal:  1,
+				SetNum:     tracks[i].SetNum,
+				Number:     fmt.Sprintf("%02d", i+1),
+			}
+		}
+		return slots
+	}
+
+	// Count tracks per group and collect the distinct groups
+	groupSizes := make(map[int]int)
+	for i := range tracks {
+		groupSizes[groupNum(&tracks[i], mode)]++
+	}
+	var groups []int
+	for g := range groupSizes {
+		groups = append(groups, g)
+	}
+	sort.Ints(groups)
+	discTotal := groups[len(groups)-1]
+
+	counters := make(map[int]int)
+	for i := range tracks {
+		g := groupNum(&tracks[i], mode)
+		counters[g]++
+		slot := trackSlot{
+			TrackNum:   counters[g],
+			TrackTotal: groupSizes[g],
+			DiscNum:    g,
+			DiscTotal:  discTotal,
+			SetNum:     tracks[i].SetNum,
+		}
+		switch mode {
+		case LayoutDiscFolders:
+			slot.Number = fmt.Sprintf("%02d", slot.TrackNum)
+			if len(groups) > 1 {
+				slot.SubDir = fmt.Sprintf("Disc %d", g)
+			}
+		case LayoutDiscTrack:
+			slot.Number = fmt.Sprintf("d%dt%02d", g, slot.TrackNum)
+		case LayoutSetPrefixed:
+			slot.Number = fmt.Sprintf("s%dt%02d", g, slot.TrackNum)
+		}
+		slots[i] = slot
+	}
+	return slots
+}
+
+// withSlot returns a copy of the data filled in with track fields positioned by the layout.
+func (p PathTemplateData) withSlot(track *Track, slot trackSlot) PathTemplateData {
+	p = p.withTrack(track, slot.TrackNum, slot.TrackTotal)
+	p.DiscNum = slot.DiscNum
+	p.DiscTotal = slot.DiscTotal
+	p.SetNum = slot.SetNum
+	p.Number = slot.Number
+	return p
+}
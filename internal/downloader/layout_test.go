@@ -0,0 +1,61 @@
+package downloader
+
+import "testing"
+
+func TestLayoutTracks(t *testing.T) {
+	tracks := []Track{
+		{SongTitle: "One", DiscNum: 1, SetNum: 1},
+		{SongTitle: "Two", DiscNum: 1, SetNum: 2},
+		{SongTitle: "Three", DiscNum: 2, SetNum: 2},
+	}
+	type slot struct {
+		number, subDir        string
+		trackNum, total, disc int
+	}
+	tests := []struct {
+		name      string
+		tracks    []Track
+		mode      string
+		discTotal int
+		want      []slot
+	}{
+		{"default is sequential", tracks, "", 1, []slot{
+			{"01", "", 1, 3, 1}, {"02", "", 2, 3, 1}, {"03", "", 3, 3, 1},
+		}},
+		{"disc folders", tracks, LayoutDiscFolders, 2, []slot{
+			{"01", "Disc 1", 1, 2, 1}, {"02", "Disc 1", 2, 2, 1}, {"01", "Disc 2", 1, 1, 2},
+		}},
+		{"disc folders with one disc", tracks[:2], LayoutDiscFolders, 1, []slot{
+			{"01", "", 1, 2, 1}, {"02", "", 2, 2, 1},
+		}},
+		{"disc track", tracks, LayoutDiscTrack, 2, []slot{
+			{"d1t01", "", 1, 2, 1}, {"d1t02", "", 2, 2, 1}, {"d2t01", "", 1, 1, 2},
+		}},
+		{"set prefixed", tracks, LayoutSetPrefixed, 2, []slot{
+			{"s1t01", "", 1, 1, 1}, {"s2t01", "", 1, 2, 2}, {"s2t02", "", 2, 2, 2},
+		}},
+		{"missing disc numbers count as disc 1", []Track{{SongTitle: "A"}, {SongTitle: "B", DiscNum: 2}}, LayoutDiscTrack, 2, []slot{
+			{"d1t01", "", 1, 1, 1}, {"d2t01", "", 1, 1, 2},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots := layoutTracks(tt.tracks, tt.mode)
+			if len(slots) != len(tt.want) {
+				t.Fatalf("layoutTracks() returned %d slots, want %d", len(slots), len(tt.want))
+			}
+			for i, s := range slots {
+				got := slot{s.Number, s.SubDir, s.TrackNum, s.TrackTotal, s.DiscNum}
+				if got != tt.want[i] {
+					t.Errorf("slot %d = %+v, want %+v", i, got, tt.want[i])
+				}
+				if s.DiscTotal != tt.discTotal {
+					t.Errorf("slot %d DiscTotal = %d, want %d", i, s.DiscTotal, tt.discTotal)
+				}
+				if s.SetNum != tt.tracks[i].SetNum {
+					t.Errorf("slot %d SetNum = %d, want %d", i, s.SetNum, tt.tracks[i].SetNum)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package downloader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"nugs-dl/internal/logger"
+	"nugs-dl/pkg/api"
+
+	"github.com/grafov/m3u8"
+)
+
+// Live capture settings.
+const (
+	progressPhaseLive       = "live"
+	defaultLiveMaxMinutes   = 360 // Stop recording after this long unless configured otherwise
+	liveMaxPlaylistFailures = 10  // Consecutive playlist errors tolerated before giving up
+	liveMaxSegmentAttempts  = 3   // Polls a failing segment is retried on before it's skipped
+	liveMinPollInterval     = 2 * time.Second
+)
+
+// isLivePlaylist reports whether a media playlist is still being written to,
+// i.e. it has no #EXT-X-ENDLIST and isn't a VOD playlist.
+func isLivePlaylist(media *m3u8.MediaPlaylist) bool {
+	return !media.Closed && media.MediaType != m3u8.VOD
+}
+
+// liveMaxDuration returns the configured recording limit.
+func (d *Downloader) liveMaxDuration() time.Duration {
+	minutes := d.config().LiveMaxDurationMinutes
+	if minutes <= 0 {
+		minutes = defaultLiveMaxMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// formatElapsed formats a duration as HH:MM:SS.
+func formatElapsed(dur time.Duration) string {
+	secs := int(dur.Seconds())
+	return fmt.Sprintf("%02d:%02d:%02d", secs/3600, secs%3600/60, secs%60)
+}
+
+// fetchSegment downloads one segment and appends it to w, returning the bytes written.
+// The body is buffered first, so a segment that fails midway leaves w untouched.
+func (d *Downloader) fetchSegment(segUrl string, w io.Writer) (int64, error) {
+	req, err := http.NewRequest(http.MethodGet, segUrl, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Add("User-Agent", userAgent)
+	do, err := d.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer do.Body.Close()
+	if do.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("bad status %s", do.Status)
+	}
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, do.Body); err != nil {
+		return 0, err
+	}
+	return buf.WriteTo(w)
+}
+
+// captureLive records a live HLS media playlist into videoPath. It keeps polling the
+// playlist, appends segments it hasn't recorded yet in order, retries a failed segment
+// on the next few polls, rides out brief playlist errors and stops at #EXT-X-ENDLIST
+// once every segment is written or given up on, or after the configured maximum
+// duration. Segments given up on are recorded on the job. The notifier is told when
+// the first segment is recorded and when the recording ends.
+func (d *Downloader) captureLive(jobID, videoPath, baseUrl, mediaPlaylistUrl, query string) error {
+	f, err := os.OpenFile(videoPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create live recording file %s: %w", videoPath, err)
+	}
+	defer f.Close()
+
+	name := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	maxDuration := d.liveMaxDuration()
+	started := time.Now()
+	seen := make(map[string]bool)    // Segments written to the file, or given up on
+	attempts := make(map[string]int) // Failed fetches per segment
+	var (
+		recorded     time.Duration // Media time written so far
+		totalBytes   int64
+		failures     int
+		skipped      int // Segments given up on
+		pollInterval = liveMinPollInterval
+	)
+	logger.Info("Starting live capture", "jobID", jobID, "maxDuration", maxDuration, "targetFile", filepath.Base(videoPath))
+
+	for {
+		media, err := d.fetchMediaPlaylist(mediaPlaylistUrl)
+		if err != nil {
+			failures++
+			logger.Warn("Live playlist fetch failed", "jobID", jobID, "attempt", failures, "error", err)
+			if failures >= liveMaxPlaylistFailures {
+				if recorded > 0 {
+					logger.Warn("Live playlist unavailable, stopping capture with what was recorded", "jobID", jobID, "recorded", formatElapsed(recorded))
+					return nil
+				}
+				return fmt.Errorf("live playlist unavailable after %d attempts: %w", failures, err)
+			}
+			time.Sleep(pollInterval)
+			continue
+		}
+		failures = 0
+		if media.TargetDuration > 0 {
+			// Poll about twice per segment, as the HLS spec suggests for live playlists
+			pollInterval = time.Duration(media.TargetDuration * float64(time.Second) / 2)
+			if pollInterval < liveMinPollInterval {
+				pollInterval = liveMinPollInterval
+			}
+		}
+
+		pending := false // A failed segment waits for the next poll
+		for _, seg := range media.Segments {
+			if seg == nil {
+				break
+			}
+			key := strings.SplitN(seg.URI, "?", 2)[0]
+			if seen[key] {
+				continue
+			}
+
+			segUrl := seg.URI
+			if !strings.HasPrefix(segUrl, "http://") && !strings.HasPrefix(segUrl, "https://") {
+				segUrl = baseUrl + seg.URI + query
+			}
+			n, err := d.fetchSegment(segUrl, f)
+			if err != nil {
+				attempts[key]++
+				if attempts[key] < liveMaxSegmentAttempts {
+					// Later segments wait too, so they're appended in order
+					logger.Warn("Failed to record live segment, retrying on the next poll", "jobID", jobID, "segment", seg.URI, "attempt", attempts[key], "error", err)
+					pending = true
+					break
+				}
+				logger.Error("Failed to record live segment, skipping it", "jobID", jobID, "segment", seg.URI, "attempts", attempts[key], "error", err)
+				d.QueueMgr.AddJobSkippedSegment(jobID, key)
+				skipped++
+				seen[key] = true
+				delete(attempts, key)
+				continue
+			}
+			seen[key] = true
+			delete(attempts, key)
+			if totalBytes == 0 {
+				d.notify(jobID, "Live recording started", name)
+			}
+			totalBytes += n
+			recorded += time.Duration(seg.Duration * float64(time.Second))
+			d.sendProgress(api.ProgressUpdate{
+				JobID:           jobID,
+				Message:         fmt.Sprintf("Recording live: %s", formatElapsed(recorded)),
+				CurrentFile:     filepath.Base(videoPath),
+				BytesDownloaded: totalBytes,
+				TotalBytes:      -1,
+				ElapsedSeconds:  recorded.Seconds(),
+				Phase:           progressPhaseLive,
+			})
+		}
+
+		if media.Closed && !pending {
+			logger.Info("Live stream ended", "jobID", jobID, "recorded", formatElapsed(recorded))
+			break
+		}
+		if time.Since(started) >= maxDuration {
+			logger.Info("Live capture reached the maximum duration, stopping", "jobID", jobID, "maxDuration", maxDuration, "recorded", formatElapsed(recorded))
+			break
+		}
+		time.Sleep(pollInterval)
+	}
+
+	if totalBytes == 0 {
+		return errors.New("no live segments were recorded")
+	}
+	message := fmt.Sprintf("%s (%s recorded)", name, formatElapsed(recorded))
+	if skipped > 0 {
+		message += fmt.Sprintf(", %d segments could not be fetched and are missing", skipped)
+		logger.Warn("Live recording has gaps", "jobID", jobID, "skippedSegments", skipped)
+	}
+	d.notify(jobID, "Live recording ended", message)
+	return nil
+}
@@ -64,6 +64,7 @@ func (d *Downloader) getAlbumMeta(containerId string) (*AlbumMeta, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode album meta response: %w", err)
 	}
+	obj.Raw = bodyBytes
 	return &obj, nil
 }
 
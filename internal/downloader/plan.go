@@ -0,0 +1,200 @@
+package downloader
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"nugs-dl/internal/logger"
+	"nugs-dl/pkg/api"
+
+	"github.com/grafov/m3u8"
+)
+
+// Kinds of files recorded in a dry-run plan.
+const (
+	planKindTrack     = "track"
+	planKindVideo     = "video"
+	planKindSingle    = "single"
+	planKindTranscode = "transcode"
+	planKindSidecar   = "sidecar" // Metadata, setlist, playlist and cue sheet files
+	planKindAudio     = "audio"   // Full audio extracted from a video
+	planKindClip      = "clip"    // Per-song clip cut from a video
+)
+
+// contentLength asks for the size of a download with a HEAD request. It returns -1
+// if the server doesn't report one.
+func (d *Downloader) contentLength(downloadUrl string) int64 {
+	req, err := http.NewRequest(http.MethodHead, downloadUrl, nil)
+	if err != nil {
+		return -1
+	}
+	req.Header.Add("Referer", playerUrl)
+	req.Header.Add("User-Agent", userAgent)
+	resp, err := d.HTTPClient.Do(req)
+	if err != nil {
+		logger.Debug("HEAD request for download size failed", "url", downloadUrl, "error", err)
+		return -1
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ContentLength <= 0 {
+		return -1
+	}
+	return resp.ContentLength
+}
+
+// planFile records a file a dry run would create, noting whether it already exists.
+func (d *Downloader) planFile(jobID string, item api.PlanItem) {
+	item.Exists, _ = FileExists(item.Path)
+	d.QueueMgr.AddJobPlanItem(jobID, item)
+}
+
+// planTrack records a track a dry run would download. HLS-only tracks have no
+// single file to ask the size of, so their size is left unknown.
+func (d *Downloader) planTrack(jobID, trackPath string, qual *Quality) {
+	size := int64(-1)
+	if qual.Format != 6 {
+		size = d.contentLength(qual.URL)
+	}
+	d.planFile(jobID, api.PlanItem{
+		Path:       trackPath,
+		Kind:       planKindTrack,
+		Format:     qual.Format,
+		FormatName: formatNames[qual.Format],
+		Specs:      qual.Specs,
+		SizeBytes:  size,
+	})
+}
+
+// estimateVideoSize estimates the size of a video variant from its bandwidth and the
+// total duration of its media playlist.
+func estimateVideoSize(media *m3u8.MediaPlaylist, bandwidth uint32) int64 {
+	if bandwidth == 0 {
+		return -1
+	}
+	var secs float64
+	for _, seg := range media.Segments {
+		if seg == nil {
+			break
+		}
+		secs += seg.Duration
+	}
+	return int64(secs * float64(bandwidth) / 8)
+}
+
+// planVideo records a video a dry run would download, with its size estimated from
+// the chosen variant's media playlist.
+func (d *Downloader) planVideo(jobID, videoPath, manifestUrl string, chosen variantInfo) error {
+	size := int64(-1)
+	manBaseUrl, query, err := getManifestBase(manifestUrl)
+	if err != nil {
+		return fmt.Errorf("failed to get manifest base URL: %w", err)
+	}
+	media, err := d.fetchMediaPlaylist(manBaseUrl + chosen.Variant.URI + query)
+	if err != nil {
+		logger.Warn("Could not read video media playlist to estimate its size", "error", err, "jobID", jobID)
+	} else if !isLivePlaylist(media) {
+		size = estimateVideoSize(media, chosen.Bandwidth)
+	}
+	d.planFile(jobID, api.PlanItem{
+		Path:       videoPath,
+		Kind:       planKindVideo,
+		FormatName: chosen.resolutionLabel(),
+		Specs:      chosen.Codec,
+		SizeBytes:  size,
+	})
+	return nil
+}
+
+// planSidecar records a metadata file a dry run would write.
+func (d *Downloader) planSidecar(jobID, path string) {
+	d.planFile(jobID, api.PlanItem{Path: path, Kind: planKindSidecar, SizeBytes: -1})
+}
+
+// planAlbumExtras records the sidecars, playlist and cue sheet, single file and
+// transcodes a dry run of a release would create.
+func (d *Downloader) planAlbumExtras(jobID, albumPath string, data PathTemplateData, entries []showEntry, downloaded []api.TrackResult, opts DownloadOptions) {
+	cfg := d.config()
+	if cfg.WriteMetadataJSON {
+		d.planSidecar(jobID, filepath.Join(albumPath, metadataSidecarFname))
+	}
+	if cfg.WriteSetlist {
+		d.planSidecar(jobID, filepath.Join(albumPath, setlistSidecarFname))
+	}
+
+	name := filepath.Base(albumPath)
+	singleFile := d.effectiveSingleFile(opts)
+	if singleFile != SingleFileReplace && len(entries) > 0 {
+		if cfg.WriteM3U8 {
+			d.planSidecar(jobID, filepath.Join(albumPath, SanitizeFilename(name)+".m3u8"))
+		}
+		if cfg.WriteCue {
+			d.planSidecar(jobID, filepath.Join(albumPath, SanitizeFilename(name)+".cue"))
+		}
+	}
+	if singleFile != SingleFileOff && len(entries) > 0 {
+		if singleFile == SingleFileAlongside {
+			name += singleFileSuffix
+		}
+		ext := strings.ToLower(filepath.Ext(entries[0].Path))
+		d.planFile(jobID, api.PlanItem{Path: filepath.Join(albumPath, SanitizeFilename(name)+ext), Kind: planKindSingle, SizeBytes: -1})
+		d.planSidecar(jobID, filepath.Join(albumPath, SanitizeFilename(name)+".cue"))
+	}
+	for _, profile := range d.config().TranscodeProfiles {
+		outDir, err := d.transcodeFolderPath(profile, data)
+		if err != nil {
+			logger.Warn("Failed to build transcode folder path for plan", "profile", profile.Name, "error", err, "jobID", jobID)
+			continue
+		}
+		for _, t := range downloaded {
+			if t.Path != "" && wantsSource(profile, t.Format) {
+				d.planFile(jobID, api.PlanItem{
+					Path:       transcodeOutPath(albumPath, outDir, t.Path, profile.Extension),
+					Kind:       planKindTranscode,
+					FormatName: profile.Name,
+					SizeBytes:  -1,
+				})
+			}
+		}
+	}
+}
+
+// planVideoExtras records the audio a dry run of a video would extract or cut at its
+// chapters, and the per-song clips.
+func (d *Downloader) planVideoExtras(jobID, videoPath string, meta *AlbArtResp, data PathTemplateData, opts DownloadOptions) {
+	cfg := d.config()
+	audioRoot := d.outPath(opts)
+	if opts.ExtractAudio || cfg.ExtractVideoAudio {
+		if path, err := d.extractedAudioPath(audioRoot, data); err == nil {
+			d.planFile(jobID, api.PlanItem{Path: path, Kind: planKindAudio, Format: splitAudioQuality.Format, FormatName: formatNames[splitAudioQuality.Format], SizeBytes: -1})
+		}
+	}
+
+	splitAudio := opts.SplitChapters || cfg.SplitVideoChapters
+	splitClips := opts.SplitClips || cfg.SplitVideoClips
+	if !splitAudio && !splitClips {
+		return
+	}
+	// The video's duration isn't known yet; only the chapter titles are needed here
+	marks := videoChapterMarks(meta.VideoChapters, 0)
+	albumDir, err := d.albumFolderPath(audioRoot, data)
+	if err != nil {
+		logger.Warn("Failed to build album folder path for plan", "error", err, "jobID", jobID)
+		return
+	}
+	clipsDir := strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + videoClipsSuffix
+	for i, m := range marks {
+		trackData := splitTrackData(data, m.Title, i+1, len(marks))
+		if splitAudio {
+			if rel, err := d.trackFileName(trackData, splitAudioQuality.Extension); err == nil {
+				d.planFile(jobID, api.PlanItem{Path: filepath.Join(albumDir, rel), Kind: planKindTrack, Format: splitAudioQuality.Format, FormatName: formatNames[splitAudioQuality.Format], Specs: splitAudioQuality.Specs, SizeBytes: -1})
+			}
+		}
+		if splitClips {
+			if rel, err := d.trackFileName(trackData, ".mp4"); err == nil {
+				d.planFile(jobID, api.PlanItem{Path: filepath.Join(clipsDir, rel), Kind: planKindClip, SizeBytes: -1})
+			}
+		}
+	}
+}
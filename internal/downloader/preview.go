@@ -0,0 +1,238 @@
+package downloader
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"nugs-dl/internal/logger"
+	"nugs-dl/pkg/api"
+)
+
+// urlTypeNames are the preview type names of each URL type.
+var urlTypeNames = map[UrlType]string{
+	ReleaseUrl:                  "release",
+	UserPlaylistHashUrl:         "playlist",
+	UserPlaylistLibUrl:          "playlist",
+	CatalogPlaylistUrl:          "playlist",
+	VideoUrlHash:                "video",
+	ArtistUrl:                   "artist",
+	ExclusiveLivestreamUrl:      "livestream",
+	WatchExclusiveLivestreamUrl: "livestream",
+	MyWebcastHashUrl:            "webcast",
+	PurchasedUrl:                "purchased",
+	MyWebcastLibUrl:             "webcast",
+	WatchReleaseUrl:             "release",
+	UnknownUrl:                  "unknown",
+}
+
+// libraryMatch reports whether a container is already in the library: downloaded by
+// a completed job, or its album folder beneath outRoot exists and isn't empty. It
+// returns the ID of the completed job and the folder found, either of which may be empty.
+func (d *Downloader) libraryMatch(meta *AlbArtResp, outRoot string) (jobID, path string) {
+	if _, jobID = d.QueueMgr.HasCompletedJobWithContainerID(fmt.Sprint(meta.ContainerID)); jobID != "" {
+		return jobID, ""
+	}
+	albumPath, err := d.albumFolderPath(outRoot, newAlbumTemplateData(meta))
+	if err != nil {
+		return "", ""
+	}
+	if entries, err := os.ReadDir(albumPath); err == nil && len(entries) > 0 {
+		return "", albumPath
+	}
+	return "", ""
+}
+
+// summarizeContainers lists containers with their library state beneath outRoot.
+func (d *Downloader) summarizeContainers(containers []*AlbArtResp, outRoot string) []api.ContainerSummary {
+	summaries := make([]api.ContainerSummary, 0, len(containers))
+	for _, c := range containers {
+		jobID, path := d.libraryMatch(c, outRoot)
+		summaries = append(summaries, api.ContainerSummary{
+			ContainerID: c.ContainerID,
+			Title:       strings.TrimRight(c.ContainerInfo, " "),
+			Date:        newAlbumTemplateData(c).Date,
+			Venue:       venueLine(c),
+			InLibrary:   jobID != "" || path != "",
+		})
+	}
+	return summaries
+}
+
+// ArtistReleases lists an artist's containers with their library state beneath
+// outRoot (empty uses the configured output path), along with the artist name.
+func (d *Downloader) ArtistReleases(artistID, outRoot string) (string, []api.ContainerSummary, error) {
+	containers, err := d.getArtistMeta(artistID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get metadata for artist %s: %w", artistID, err)
+	}
+	if len(containers) == 0 {
+		return "", nil, fmt.Errorf("no containers found for artist %s", artistID)
+	}
+	if outRoot == "" {
+		outRoot = d.config().OutPath
+	}
+	return containers[0].ArtistName, d.summarizeContainers(containers, outRoot), nil
+}
+
+// PreviewURL describes what a URL points to without creating a job. Problems are
+// reported in the Error field, so one bad URL doesn't spoil a batch.
+func (d *Downloader) PreviewURL(rawUrl string) api.URLPreview {
+	preview := api.URLPreview{Url: rawUrl}
+	id, urlType := CheckUrl(rawUrl)
+	if urlType == CatalogPlaylistUrl {
+		resolvedUrl, err := d.resolveRedirectURL(rawUrl)
+		if err != nil {
+			preview.Type = urlTypeNames[urlType]
+			preview.Error = fmt.Sprintf("failed to resolve short URL: %v", err)
+			return preview
+		}
+		preview.ResolvedUrl = resolvedUrl
+		id, urlType = CheckUrl(resolvedUrl)
+		if urlType == UserPlaylistHashUrl || urlType == UserPlaylistLibUrl {
+			urlType = CatalogPlaylistUrl // Resolved short links are catalog playlists
+		}
+	}
+	preview.Type = urlTypeNames[urlType]
+	preview.ID = id
+
+	var err error
+	switch urlType {
+	case ReleaseUrl, VideoUrlHash, ExclusiveLivestreamUrl, WatchExclusiveLivestreamUrl, MyWebcastHashUrl, MyWebcastLibUrl, WatchReleaseUrl:
+		err = d.previewContainer(&preview, id)
+	case PurchasedUrl:
+		showID := ""
+		if parsedUrl, perr := url.Parse(rawUrl); perr == nil {
+			showID = parsedUrl.Query().Get("showID")
+		}
+		if showID == "" {
+			err = fmt.Errorf("could not find showID in purchased URL")
+		} else {
+			preview.ID = showID
+			err = d.previewContainer(&preview, showID)
+		}
+	case UserPlaylistHashUrl, UserPlaylistLibUrl, CatalogPlaylistUrl:
+		err = d.previewPlaylist(&preview, id, urlType == CatalogPlaylistUrl)
+	case ArtistUrl:
+		err = d.previewArtist(&preview, id)
+	default:
+		err = fmt.Errorf("unsupported URL: %s", rawUrl)
+	}
+	if err != nil {
+		logger.Warn("URL preview failed", "url", rawUrl, "error", err)
+		preview.Error = err.Error()
+	}
+	return preview
+}
+
+// previewContainer fills a preview from a container's metadata.
+func (d *Downloader) previewContainer(preview *api.URLPreview, containerID string) error {
+	albumMeta, err := d.getAlbumMeta(containerID)
+	if err != nil {
+		return fmt.Errorf("failed to get metadata for container %s: %w", containerID, err)
+	}
+	if albumMeta.Response == nil {
+		return fmt.Errorf("API returned empty response for container %s", containerID)
+	}
+	meta := albumMeta.Response
+	data := newAlbumTemplateData(meta)
+
+	preview.ContainerID = meta.ContainerID
+	preview.Title = data.ContainerInfo
+	preview.ArtistName = meta.ArtistName
+	preview.Date = data.Date
+	preview.Venue = venueLine(meta)
+	preview.ArtworkURL = extractArtworkUrl(meta)
+	preview.HasVideo = getVideoSkuID(meta, d.config().VideoFormat, "") != 0
+
+	for _, p := range meta.Products {
+		if p.SkuID != 0 {
+			preview.Products = append(preview.Products, api.ProductSKU{SkuID: p.SkuID, FormatStr: p.FormatStr})
+		}
+	}
+	for _, p := range meta.ProductFormatList {
+		if p != nil && p.SkuID != 0 {
+			preview.Products = append(preview.Products, api.ProductSKU{SkuID: p.SkuID, FormatStr: p.FormatStr, PfType: p.PfType})
+		}
+	}
+
+	tracks := meta.Tracks
+	if len(tracks) == 0 {
+		tracks = meta.Songs
+	}
+	if preview.Tracks, _, err = d.previewTrackList(tracks, nil, ""); err != nil {
+		return err
+	}
+	if len(tracks) == 0 && !preview.HasVideo {
+		preview.Warnings = append(preview.Warnings, "Release has no tracks or videos")
+	}
+
+	if start, ok := liveEventStart(meta.ProductFormatList); ok && time.Now().Before(start) {
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("Livestream starts %s; the job will wait as scheduled", start.Format(time.RFC3339)))
+	}
+
+	jobID, path := d.libraryMatch(meta, d.artistOutRoot(meta, DownloadOptions{}))
+	if jobID != "" || path != "" {
+		preview.InLibrary = true
+		preview.CompletedJobID = jobID
+		preview.LibraryPath = path
+		if jobID != "" {
+			preview.Warnings = append(preview.Warnings, fmt.Sprintf("Already downloaded by job %s", jobID))
+		} else {
+			preview.Warnings = append(preview.Warnings, fmt.Sprintf("Already in the library at %s", path))
+		}
+	}
+	return nil
+}
+
+// previewPlaylist fills a preview from playlist metadata. User playlists need a login.
+func (d *Downloader) previewPlaylist(preview *api.URLPreview, plistId string, isCatalogPlist bool) error {
+	legacyToken := ""
+	if !isCatalogPlist {
+		sess, err := d.cachedSession()
+		if err != nil {
+			return err
+		}
+		legacyToken = sess.LegacyToken
+	}
+	meta, err := d.getPlistMeta(plistId, d.config().Email, legacyToken, isCatalogPlist)
+	if err != nil {
+		return fmt.Errorf("failed to get metadata for playlist %s: %w", plistId, err)
+	}
+	if meta.Response == nil || len(meta.Response.Items) == 0 {
+		return fmt.Errorf("playlist %s is empty or returned no data", plistId)
+	}
+	preview.Title = meta.Response.PlayListName
+	for i, item := range meta.Response.Items {
+		preview.Tracks = append(preview.Tracks, api.TrackPreview{
+			Index:           i + 1,
+			Number:          fmt.Sprintf("%02d", i+1),
+			DiscNum:         1,
+			SetNum:          item.Track.SetNum,
+			Title:           item.Track.SongTitle,
+			DurationSeconds: item.Track.TotalRunningTime,
+			Selected:        true,
+		})
+	}
+	logger.Debug("Previewed playlist", "playlistID", plistId, "tracks", len(preview.Tracks))
+	return nil
+}
+
+// previewArtist fills a preview from an artist's containers.
+func (d *Downloader) previewArtist(preview *api.URLPreview, artistId string) error {
+	containers, err := d.getArtistMeta(artistId)
+	if err != nil {
+		return fmt.Errorf("failed to get metadata for artist %s: %w", artistId, err)
+	}
+	if len(containers) == 0 {
+		return fmt.Errorf("no containers found for artist %s", artistId)
+	}
+	preview.ArtistName = containers[0].ArtistName
+	preview.Title = containers[0].ArtistName
+	preview.ContainerCount = len(containers)
+	preview.Containers = d.summarizeContainers(containers, d.config().OutPath)
+	preview.Warnings = append(preview.Warnings, fmt.Sprintf("This is an artist page: all %d releases would be downloaded", len(containers)))
+	return nil
+}
@@ -40,30 +40,62 @@ func queryQuality(streamUrl string) *Quality {
 	return nil
 }
 
-// getTrackQual selects the desired quality from available ones, handling fallbacks.
-// (Moved from main.go)
-func getTrackQual(quals []*Quality, wantFmt int) *Quality {
-	// Try to find the exact wanted format
-	for _, quality := range quals {
-		if quality.Format == wantFmt {
-			return quality
+// isLossyFormat reports whether a format code is a lossy (AAC) format.
+func isLossyFormat(format int) bool {
+	return format == 5 || format == 6
+}
+
+// qualityChain returns the ordered list of acceptable formats for a job.
+// A per-job list wins over the configured formatFallback; otherwise the chain is
+// derived from the wanted format via trackFallback. In strict mode a derived chain
+// stops before degrading to a lossy format.
+func (d *Downloader) qualityChain(opts DownloadOptions) []int {
+	if len(opts.Formats) > 0 {
+		return opts.Formats
+	}
+	wantFmt := opts.Format
+	if wantFmt == 0 {
+		if len(d.config().FormatFallback) > 0 {
+			return d.config().FormatFallback
 		}
+		wantFmt = d.config().Format
 	}
+	chain := []int{wantFmt}
+	seen := map[int]bool{wantFmt: true}
+	for next, ok := trackFallback[wantFmt]; ok && !seen[next]; next, ok = trackFallback[next] {
+		if opts.StrictQuality && isLossyFormat(next) && !isLossyFormat(wantFmt) {
+			break
+		}
+		chain = append(chain, next)
+		seen[next] = true
+	}
+	return chain
+}
 
-	// If exact not found, try fallback
-	fbFmt, hasFallback := trackFallback[wantFmt]
-	if hasFallback {
-		logger.Info("Track quality format unavailable, falling back", "wantedFormat", wantFmt, "fallbackFormat", fbFmt)
+// getTrackQual selects the first available quality in the preference chain.
+// If nothing in the chain is available, strict mode returns nil; otherwise the
+// first available quality is used.
+// (Moved from main.go)
+func getTrackQual(quals []*Quality, chain []int, strict bool) *Quality {
+	for i, wantFmt := range chain {
 		for _, quality := range quals {
-			if quality.Format == fbFmt {
+			if quality.Format == wantFmt {
+				if i > 0 {
+					logger.Info("Track quality format unavailable, falling back", "wantedFormat", chain[0], "fallbackFormat", wantFmt)
+				}
 				return quality
 			}
 		}
 	}
 
-	// If fallback also not found, maybe return the first available or highest quality?
+	if strict {
+		logger.Warn("No track quality in the preference chain is available and strict quality is enabled", "chain", chain)
+		return nil
+	}
+
+	// If no preferred format is available, select the first available quality
 	if len(quals) > 0 {
-		logger.Info("Fallback track quality format also unavailable, selecting first available quality.", "selectedFormat", quals[0].Format)
+		logger.Info("Preferred track quality formats unavailable, selecting first available quality.", "chain", chain, "selectedFormat", quals[0].Format)
 		return quals[0]
 	}
 
@@ -186,19 +218,17 @@ func (d *Downloader) sendProgress(update api.ProgressUpdate) {
 	}
 }
 
-// processTrack handles fetching metadata, selecting quality, and downloading a single track.
-// (Refactored from processTrack in main.go)
-func (d *Downloader) processTrack(jobID string, folPath string, trackNum, trackTotal int, track *Track, streamParams *StreamParams) error {
-	// Calculate track-based progress percentage (completed tracks / total tracks * 100)
-	trackProgressPercentage := float64(trackNum-1) / float64(trackTotal) * 100.0
-	wantFmt := d.Config.Format // Get desired format from downloader config
-	var (
-		quals      []*Quality
-		chosenQual *Quality
-		err        error
-	)
+// formatFolder is an output folder for one format of a job.
+// Format 0 denotes the primary format, chosen via the preference chain.
+type formatFolder struct {
+	Format int
+	Path   string
+}
 
-	// --- Get Stream URLs for different formats ---
+// probeTrackQualities queries the stream API for every platform ID and returns
+// the distinct qualities offered for a track. The result is shared by all output formats.
+func (d *Downloader) probeTrackQualities(jobID string, track *Track, streamParams *StreamParams) ([]*Quality, error) {
+	var quals []*Quality
 	// Try formats 1, 4, 7, 10 to cover different possibilities
 	for _, apiFmtId := range [4]int{1, 4, 7, 10} {
 		streamUrl, err := d.getStreamMeta(track.TrackID, 0, apiFmtId, streamParams)
@@ -226,114 +256,308 @@ func (d *Downloader) processTrack(jobID string, folPath string, trackNum, trackT
 
 	if len(quals) == 0 {
 		logger.Error("No valid stream URLs found for track", "trackID", track.TrackID, "songTitle", track.SongTitle, "jobID", jobID)
-		return errors.New("no valid stream URLs found for track")
+		return nil, errors.New("no valid stream URLs found for track")
+	}
+	return quals, nil
+}
+
+// findQuality returns the quality with exactly the given format, or nil.
+// HLS (format 6) satisfies a request for AAC (format 5).
+func findQuality(quals []*Quality, format int) *Quality {
+	for _, q := range quals {
+		if q.Format == format {
+			return q
+		}
+	}
+	if format == 5 {
+		return findQuality(quals, 6)
+	}
+	return nil
+}
+
+// processTrack handles fetching metadata, selecting quality, and downloading a single track.
+// Stream URLs are probed once and shared by every folder in folders: the first
+// entry gets the format picked by the preference chain, the others their exact format.
+// trackNum/trackTotal drive progress reporting; trackData carries the layout-positioned
+// fields used for the filename template and tags. The result of the primary format is returned.
+// (Refactored from processTrack in main.go)
+func (d *Downloader) processTrack(jobID string, folders []formatFolder, trackNum, trackTotal int, track *Track, streamParams *StreamParams, trackData PathTemplateData, opts DownloadOptions) (*api.TrackResult, error) {
+	chain := d.qualityChain(opts) // Ordered list of acceptable formats
+
+	quals, err := d.probeTrackQualities(jobID, track, streamParams)
+	if err != nil {
+		return nil, err
 	}
 
 	// --- Select Quality / Handle HLS ---
 	isHlsOnly := checkIfHlsOnly(quals)
-
-	// --- Prepare Filename (determine path first, extension might change for HLS) ---
-	// Use a placeholder extension for HLS initially, downloadHls will create final path.
-	initialExtension := ".tmp_download"
-	if !isHlsOnly {
-		// For non-HLS, we need chosenQual now to get the correct extension
-		chosenQual = getTrackQual(quals, wantFmt)
+	var chosenQual *Quality
+	if isHlsOnly {
+		// HLS audio is remuxed to AAC, described by the HLS entry in qualityMap
+		chosenQual = findQuality(quals, 6)
+		if opts.StrictQuality && !formatInChain(chain, 5) && !formatInChain(chain, 6) {
+			logger.Error("Track is only available as HLS AAC and strict quality is enabled", "trackID", track.TrackID, "songTitle", track.SongTitle, "chain", chain, "jobID", jobID)
+			return nil, fmt.Errorf("strict quality: track is only available as AAC (wanted %v)", chain)
+		}
+	} else {
+		chosenQual = getTrackQual(quals, chain, opts.StrictQuality)
 		if chosenQual == nil {
-			logger.Error("Could not determine a suitable download quality/format for track", "trackID", track.TrackID, "songTitle", track.SongTitle, "wantedFormat", wantFmt, "jobID", jobID)
-			return errors.New("could not determine a suitable download quality/format")
+			logger.Error("Could not determine a suitable download quality/format for track", "trackID", track.TrackID, "songTitle", track.SongTitle, "chain", chain, "jobID", jobID)
+			if opts.StrictQuality {
+				return nil, fmt.Errorf("strict quality: none of the formats %v are available", chain)
+			}
+			return nil, errors.New("could not determine a suitable download quality/format")
 		}
-		initialExtension = chosenQual.Extension
 	}
-	trackFname := fmt.Sprintf(
-		"%02d. %s%s", trackNum, SanitizeFilename(track.SongTitle), initialExtension,
-	)
-	trackPath := filepath.Join(folPath, trackFname)
 
-	if isHlsOnly {
-		logger.Info("Track is HLS-only. Only AAC is available.", "trackID", track.TrackID, "songTitle", track.SongTitle, "jobID", jobID)
-		// Need the *master* playlist URL to start the HLS process
-		masterPlaylistUrl := ""
-		for _, q := range quals {
-			if q.Format == 6 { // Find the original master playlist URL
-				masterPlaylistUrl = q.URL
-				break
+	var primary *api.TrackResult
+	var firstErr error
+	for i, folder := range folders {
+		qual := chosenQual
+		if i > 0 {
+			qual = findQuality(quals, folder.Format)
+			if qual == nil {
+				logger.Warn("Additional format not available for track, skipping", "trackID", track.TrackID, "songTitle", track.SongTitle, "format", folder.Format, "jobID", jobID)
+				d.QueueMgr.AddJobTrackResult(jobID, api.TrackResult{TrackNum: trackNum, Title: track.SongTitle, Wanted: folder.Format, Extra: true, Error: fmt.Sprintf("format %s not available", formatNames[folder.Format])})
+				continue
 			}
 		}
-		if masterPlaylistUrl == "" {
-			logger.Error("Could not find master playlist URL for HLS track", "trackID", track.TrackID, "songTitle", track.SongTitle, "jobID", jobID)
-			return errors.New("could not find master playlist URL for HLS track")
+		// Record which format was actually chosen, so degraded shows can be found later
+		result := api.TrackResult{
+			TrackNum:   trackNum,
+			Title:      track.SongTitle,
+			Format:     qual.Format,
+			FormatName: formatNames[qual.Format],
+			Specs:      qual.Specs,
+			Wanted:     chain[0],
+		}
+		if i > 0 {
+			result.Wanted = folder.Format
+			result.Extra = true
 		}
+		result.Degraded = qual.Format != result.Wanted && !(result.Wanted == 5 && qual.Format == 6)
+		if err := d.downloadTrackQuality(jobID, folder.Path, trackNum, trackTotal, track, trackData, qual, &result, opts.DryRun, opts.ReplaceLossy); err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if i == 0 {
+			primary = &result
+		}
+	}
+	return primary, firstErr
+}
+
+// downloadTrackQuality downloads one quality of a track into folPath, then tags it
+// and records the result on the job. result.Path is set to the file written.
+// In a dry run the file is only added to the job's plan. With replaceLossy, an existing
+// lossy file is overwritten by a lossless download and lossy copies of the track under
+// another extension are removed.
+func (d *Downloader) downloadTrackQuality(jobID, folPath string, trackNum, trackTotal int, track *Track, trackData PathTemplateData, qual *Quality, result *api.TrackResult, dryRun, replaceLossy bool) error {
+	// Calculate track-based progress percentage (completed tracks / total tracks * 100)
+	trackProgressPercentage := float64(trackNum-1) / float64(trackTotal) * 100.0
+
+	// --- Prepare Filename (the chosen quality determines the extension) ---
+	extension := qual.Extension
+	if qual.Format == 6 {
+		// HLS audio is remuxed to AAC, described by the HLS entry in qualityMap
+		extension = qualityMap[".m3u8?"].Extension
+	}
+	trackData = trackData.withQuality(qual)
+	trackRelPath, err := d.trackFileName(trackData, extension)
+	if err != nil {
+		logger.Error("Failed to render track filename template", "trackID", track.TrackID, "error", err, "jobID", jobID)
+		return err
+	}
+	trackPath := filepath.Join(folPath, trackRelPath)
+	trackFname := filepath.Base(trackPath)
+	result.Path = trackPath
+	if dryRun {
+		d.planTrack(jobID, trackPath, qual)
+		return nil
+	}
+	// The track template may contain subdirectories
+	if err := MakeDirs(filepath.Dir(trackPath)); err != nil {
+		return err
+	}
+
+	if qual.Format == 6 {
+		logger.Info("Track is HLS-only. Only AAC is available.", "trackID", track.TrackID, "songTitle", track.SongTitle, "jobID", jobID)
 		// Before HLS download call:
 		d.sendProgress(api.ProgressUpdate{
-			JobID: jobID, 
-			Message: fmt.Sprintf("Downloading HLS track %d/%d", trackNum, trackTotal), 
-			CurrentFile: trackFname,
-			Percentage: trackProgressPercentage,
+			JobID:        jobID,
+			Message:      fmt.Sprintf("Downloading HLS track %d/%d", trackNum, trackTotal),
+			CurrentFile:  trackFname,
+			Percentage:   trackProgressPercentage,
 			CurrentTrack: trackNum,
-			TotalTracks: trackTotal,
+			TotalTracks:  trackTotal,
 		})
-		// Call the HLS download function
-		err = d.downloadHls(jobID, trackPath, masterPlaylistUrl)
+		// The HLS quality URL is the original master playlist URL
+		err = d.downloadHls(jobID, trackPath, qual.URL)
+		if err == nil {
+			d.tagTrack(jobID, trackPath, trackData)
+			d.QueueMgr.AddJobTrackResult(jobID, *result)
+		}
 		// Whether HLS succeeds or fails, we return the result here.
 		return err
-	} else {
-		// Non-HLS path
-		// chosenQual is already set from above
-		if chosenQual == nil { // Safety check
-			logger.Error("Internal error: chosenQual is nil in non-HLS path", "trackID", track.TrackID, "songTitle", track.SongTitle, "jobID", jobID)
-			return errors.New("internal error: chosenQual is nil in non-HLS path")
+	}
+
+	// --- Check Existence (for non-HLS) ---
+	exists, err := FileExists(trackPath) // Use utility function
+	if err != nil {
+		logger.Error("Failed to check if track exists", "path", trackPath, "error", err, "jobID", jobID)
+		return fmt.Errorf("failed to check if track exists %s: %w", trackPath, err)
+	}
+	upgrade := replaceLossy && !isLossyFormat(qual.Format)
+	if exists && !(upgrade && d.isLossyFile(trackPath)) {
+		logger.Info("Track already exists, skipping download", "trackNumber", trackNum, "totalTracks", trackTotal, "filename", trackFname, "jobID", jobID)
+		result.Existing = true
+		d.QueueMgr.AddJobTrackResult(jobID, *result)
+		return nil // Skip download
+	}
+	// A lossy file in the way is only replaced once the new one is complete
+	downloadPath := trackPath
+	if exists {
+		logger.Info("Replacing lossy copy of track", "filename", trackFname, "format", formatNames[qual.Format], "jobID", jobID)
+		downloadPath = strings.TrimSuffix(trackPath, extension) + ".upgrading" + extension
+	}
+
+	// --- Download (for non-HLS) ---
+	logger.Info("Downloading track",
+		"trackNumber", trackNum,
+		"totalTracks", trackTotal,
+		"songTitle", track.SongTitle,
+		"qualitySpecs", qual.Specs,
+		"jobID", jobID)
+	// Before download call:
+	d.sendProgress(api.ProgressUpdate{
+		JobID:        jobID,
+		Message:      fmt.Sprintf("Downloading track %d/%d", trackNum, trackTotal),
+		CurrentFile:  trackFname,
+		Percentage:   trackProgressPercentage,
+		CurrentTrack: trackNum,
+		TotalTracks:  trackTotal,
+	})
+	// Make download call pass jobID
+	err = d.downloadFile(jobID, downloadPath, qual.URL)
+	if err == nil && downloadPath != trackPath {
+		err = os.Rename(downloadPath, trackPath)
+	}
+
+	if err != nil {
+		logger.Error("Download failed for track, removing partial file", "filename", trackFname, "error", err, "jobID", jobID)
+		os.Remove(downloadPath)
+		return fmt.Errorf("download failed for track %s: %w", trackFname, err)
+	}
+	if upgrade {
+		d.removeLossyCopies(jobID, trackPath)
+	}
+
+	logger.Info("Successfully downloaded track", "trackNumber", trackNum, "filename", trackFname, "jobID", jobID)
+	d.tagTrack(jobID, trackPath, trackData)
+	d.QueueMgr.AddJobTrackResult(jobID, *result)
+	// After download call: calculate progress based on completed tracks
+	completedTrackProgress := float64(trackNum) / float64(trackTotal) * 100.0
+	d.sendProgress(api.ProgressUpdate{
+		JobID:        jobID,
+		Message:      fmt.Sprintf("Finished track %d/%d", trackNum, trackTotal),
+		CurrentFile:  trackFname,
+		Percentage:   completedTrackProgress,
+		CurrentTrack: trackNum,
+		TotalTracks:  trackTotal,
+	})
+	return nil
+}
+
+// isLossyFile reports whether an audio file holds a lossy codec, probed with ffprobe.
+// Files that can't be probed are taken as not lossy, so they're never replaced.
+func (d *Downloader) isLossyFile(path string) bool {
+	codec, err := d.probeAudioCodec(path)
+	if err != nil {
+		logger.Warn("Could not probe existing track codec", "path", path, "error", err)
+		return false
+	}
+	switch codec {
+	case "aac", "mp3", "opus", "vorbis":
+		return true
+	}
+	return false
+}
+
+// removeLossyCopies deletes lossy copies of a track that sit next to it under another
+// extension, left over from before it was upgraded to a lossless format.
+func (d *Downloader) removeLossyCopies(jobID, trackPath string) {
+	ext := filepath.Ext(trackPath)
+	for _, other := range []string{".m4a", ".mp3"} {
+		if strings.EqualFold(other, ext) {
+			continue
 		}
+		path := strings.TrimSuffix(trackPath, ext) + other
+		if exists, _ := FileExists(path); exists && d.isLossyFile(path) {
+			if err := os.Remove(path); err != nil {
+				logger.Warn("Failed to remove lossy copy of upgraded track", "path", path, "error", err, "jobID", jobID)
+				continue
+			}
+			logger.Info("Removed lossy copy of upgraded track", "path", path, "jobID", jobID)
+		}
+	}
+}
 
-		// --- Check Existence (for non-HLS) ---
-		exists, err := FileExists(trackPath) // Use utility function
+// extraFormatFolders returns the output folders for a job: the primary folder first,
+// followed by one folder per additional format. Formats equal to the preferred one
+// or listed twice are skipped. render produces the folder for a format; if two
+// formats render to the same folder the format name is appended.
+func (d *Downloader) extraFormatFolders(primary string, render func(format int) (string, error), opts DownloadOptions) ([]formatFolder, error) {
+	folders := []formatFolder{{Path: primary}}
+	used := map[string]bool{primary: true}
+	seen := map[int]bool{d.qualityChain(opts)[0]: true}
+	for _, f := range opts.ExtraFormats {
+		if seen[f] {
+			logger.Debug("Skipping extra format already being downloaded", "format", f)
+			continue
+		}
+		seen[f] = true
+		path, err := render(f)
 		if err != nil {
-			logger.Error("Failed to check if track exists", "path", trackPath, "error", err, "jobID", jobID)
-			return fmt.Errorf("failed to check if track exists %s: %w", trackPath, err)
-		}
-		if exists {
-			logger.Info("Track already exists, skipping download", "trackNumber", trackNum, "totalTracks", trackTotal, "filename", trackFname, "jobID", jobID)
-			return nil // Skip download
-		}
-
-		// --- Download (for non-HLS) ---
-		logger.Info("Downloading track",
-			"trackNumber", trackNum,
-			"totalTracks", trackTotal,
-			"songTitle", track.SongTitle,
-			"qualitySpecs", chosenQual.Specs,
-			"jobID", jobID)
-		// Before download call:
-		d.sendProgress(api.ProgressUpdate{
-			JobID: jobID, 
-			Message: fmt.Sprintf("Downloading track %d/%d", trackNum, trackTotal), 
-			CurrentFile: trackFname,
-			Percentage: trackProgressPercentage,
-			CurrentTrack: trackNum,
-			TotalTracks: trackTotal,
-		})
-		// Make download call pass jobID
-		err = d.downloadFile(jobID, trackPath, chosenQual.URL)
+			return nil, err
+		}
+		if used[path] {
+			path = path + " [" + formatNames[f] + "]"
+		}
+		used[path] = true
+		if !opts.DryRun {
+			if err := MakeDirs(path); err != nil {
+				return nil, err
+			}
+		}
+		folders = append(folders, formatFolder{Format: f, Path: path})
+	}
+	return folders, nil
+}
 
-		if err != nil {
-			logger.Error("Download failed for track, removing partial file", "filename", trackFname, "error", err, "jobID", jobID)
-			os.Remove(trackPath)
-			return fmt.Errorf("download failed for track %s: %w", trackFname, err)
+// formatInChain reports whether format appears in the preference chain.
+func formatInChain(chain []int, format int) bool {
+	for _, f := range chain {
+		if f == format {
+			return true
 		}
+	}
+	return false
+}
 
-		logger.Info("Successfully downloaded track", "trackNumber", trackNum, "filename", trackFname, "jobID", jobID)
-		// After download call: calculate progress based on completed tracks
-		completedTrackProgress := float64(trackNum) / float64(trackTotal) * 100.0
-		d.sendProgress(api.ProgressUpdate{
-			JobID: jobID, 
-			Message: fmt.Sprintf("Finished track %d/%d", trackNum, trackTotal), 
-			CurrentFile: trackFname, 
-			Percentage: completedTrackProgress,
-			CurrentTrack: trackNum,
-			TotalTracks: trackTotal,
-		})
-		return nil // Explicitly return nil on non-HLS success
+// tagTrack writes metadata tags to a downloaded track unless tagging is disabled.
+// Tagging failures are logged but do not fail the track.
+func (d *Downloader) tagTrack(jobID, trackPath string, trackData PathTemplateData) {
+	if d.config().SkipTags {
+		return
+	}
+	if err := d.writeTags(trackPath, trackTags(trackData)); err != nil {
+		logger.Warn("Failed to write tags to track", "path", trackPath, "error", err, "jobID", jobID)
 	}
-	// Code below the if/else is now truly unreachable
 }
 
 // --- Album / Artist / Playlist Processing ---
@@ -419,9 +643,10 @@ func getLstreamSku(products []*ProductFormatList) int {
 // (Refactored from album in main.go)
 func (d *Downloader) processAlbum(jobID string, albumID string, opts DownloadOptions, streamParams *StreamParams, preloadedMeta *AlbArtResp) error {
 	var (
-		meta   *AlbArtResp
-		tracks []Track
-		err    error
+		meta    *AlbArtResp
+		rawMeta []byte // Container response as returned by the API, for the metadata sidecar
+		tracks  []Track
+		err     error
 	)
 
 	logger.Debug("[processAlbum] Entry",
@@ -429,11 +654,11 @@ func (d *Downloader) processAlbum(jobID string, albumID string, opts DownloadOpt
 		"albumID", albumID, // This is the containerID for livestreams
 		"opts.ForceVideo", opts.ForceVideo,
 		"opts.SkipVideos", opts.SkipVideos,
-		"config.ForceVideo", d.Config.ForceVideo,
-		"config.SkipVideos", d.Config.SkipVideos,
-		"config.VideoFormat", d.Config.VideoFormat,
-		"config.AudioFormat", d.Config.Format,
-		"config.LiveVideoPath", d.Config.LiveVideoPath,
+		"config.ForceVideo", d.config().ForceVideo,
+		"config.SkipVideos", d.config().SkipVideos,
+		"config.VideoFormat", d.config().VideoFormat,
+		"config.AudioFormat", d.config().Format,
+		"config.LiveVideoPath", d.config().LiveVideoPath,
 	)
 
 	if preloadedMeta != nil {
@@ -451,6 +676,7 @@ func (d *Downloader) processAlbum(jobID string, albumID string, opts DownloadOpt
 			return fmt.Errorf("API returned empty response for album %s", albumID)
 		}
 		meta = albumMeta.Response
+		rawMeta = albumMeta.Raw
 	}
 
 	if meta != nil {
@@ -464,14 +690,19 @@ func (d *Downloader) processAlbum(jobID string, albumID string, opts DownloadOpt
 		logger.Warn("[processAlbum] Metadata (meta) is nil after fetching/preloading", "jobID", jobID, "albumID", albumID)
 	}
 
+	if meta != nil {
+		d.applyArtistOverrides(jobID, meta, &opts)
+	}
+
 	// Update Job with ContainerID if available
 	var currentJobContainerID string
 	if meta != nil && meta.ContainerID != 0 {
 		currentJobContainerID = strconv.Itoa(meta.ContainerID)
 		d.QueueMgr.UpdateJobContainerID(jobID, currentJobContainerID)
 
-		// Check if this content has already been downloaded successfully
-		if completed, originalJobID := d.QueueMgr.HasCompletedJobWithContainerID(currentJobContainerID); completed {
+		// Check if this content has already been downloaded successfully.
+		// Jobs with a track selection may legitimately grab other parts of the same release.
+		if completed, originalJobID := d.QueueMgr.HasCompletedJobWithContainerID(currentJobContainerID); completed && opts.Selection == nil {
 			errMsg := fmt.Sprintf("Content with ContainerID '%s' already downloaded in JobID '%s'", currentJobContainerID, originalJobID)
 			logger.Info("[Downloader-processAlbum] Duplicate completed content detected", "jobID", jobID, "containerID", currentJobContainerID, "originalJobID", originalJobID)
 			// Return a wrapped ErrDuplicateCompleted to allow type checking by the caller (worker)
@@ -494,7 +725,7 @@ func (d *Downloader) processAlbum(jobID string, albumID string, opts DownloadOpt
 	trackTotal := len(tracks)
 
 	// Check for video
-	skuID := getVideoSkuID(meta, d.Config.VideoFormat, jobID) // Use 'meta' which is *AlbArtResp
+	skuID := getVideoSkuID(meta, d.videoFormat(opts), jobID) // Use 'meta' which is *AlbArtResp
 
 	if skuID == 0 && trackTotal < 1 {
 		logger.Error("Release has no tracks or videos", "albumID", albumID, "jobID", jobID)
@@ -506,24 +737,24 @@ func (d *Downloader) processAlbum(jobID string, albumID string, opts DownloadOpt
 		"jobID", jobID,
 		"albumID", albumID,
 		"opts.ForceVideo", opts.ForceVideo,
-		"d.Config.ForceVideo", d.Config.ForceVideo,
+		"d.config().ForceVideo", d.config().ForceVideo,
 		"opts.SkipVideos", opts.SkipVideos,
-		"d.Config.SkipVideos", d.Config.SkipVideos,
+		"d.config().SkipVideos", d.config().SkipVideos,
 		"meta.ContainerTypeStr", meta.ContainerTypeStr,
 	)
 
-    videoSkuID := getVideoSkuID(meta, d.Config.VideoFormat, jobID) // Use 'meta' which is *AlbArtResp
+    videoSkuID := getVideoSkuID(meta, d.videoFormat(opts), jobID) // Use 'meta' which is *AlbArtResp
     logger.Info("[processAlbum] getVideoSkuID result", "jobID", jobID, "albumID", albumID, "videoSkuID", videoSkuID)
     logger.Info("[processAlbum] After getVideoSkuID call", "jobID", jobID, "albumID", albumID, "returnedVideoSkuID", videoSkuID)
 
 	if videoSkuID != 0 { // Video exists, use videoSkuID from our comprehensive check
 		if opts.SkipVideos {
 			logger.Info("Skipping video for album/show due to options", "albumID", albumID, "jobID", jobID)
-		} else if !opts.ForceVideo && !d.Config.ForceVideo && meta.ContainerTypeStr != "Video" && meta.ContainerTypeStr != "Bundle" && meta.ContainerTypeStr != "Show" { 
+		} else if !opts.ForceVideo && !d.config().ForceVideo && meta.ContainerTypeStr != "Video" && meta.ContainerTypeStr != "Bundle" && meta.ContainerTypeStr != "Show" { 
 			if trackTotal < 1 {
 				return nil
 			}
-		} else if opts.ForceVideo || d.Config.ForceVideo || trackTotal < 1 {
+		} else if opts.ForceVideo || d.config().ForceVideo || trackTotal < 1 {
 			logger.Info("Processing video for album/show", "albumID", albumID, "jobID", jobID, "forceVideo", opts.ForceVideo, "trackTotal", trackTotal)
 			err = d.processVideo(jobID, albumID, "", opts, streamParams, meta, false)
 			if err != nil {
@@ -535,6 +766,11 @@ func (d *Downloader) processAlbum(jobID string, albumID string, opts DownloadOpt
 		}
 		// If video exists but not forced and tracks exist, fall through to download tracks
 	}
+	if trackTotal == 0 {
+		// Only reached for a video-only release whose video was skipped
+		logger.Info("Release has no tracks to download", "albumID", albumID, "jobID", jobID)
+		return nil
+	}
 
 	// --- Download Tracks ---
 	albumFolder := meta.ArtistName + " - " + strings.TrimRight(meta.ContainerInfo, " ")
@@ -543,28 +779,115 @@ func (d *Downloader) processAlbum(jobID string, albumID string, opts DownloadOpt
 	// Update job title with the proper album information
 	d.QueueMgr.UpdateJobTitle(jobID, albumFolder)
 
-	// Sanitize and potentially shorten folder name (keep original logic?)
-	albumPath := filepath.Join(d.Config.OutPath, SanitizeFilename(albumFolder))
-	err = MakeDirs(albumPath) // TODO: Move MakeDirs to utils
+	// Render the album folder from the configured template
+	tmplData := newAlbumTemplateData(meta)
+	albumPath, err := d.albumFolderPath(d.outPath(opts), tmplData)
+	if err != nil {
+		return fmt.Errorf("failed to build album folder path: %w", err)
+	}
+	if !opts.DryRun {
+		err = MakeDirs(albumPath) // TODO: Move MakeDirs to utils
+		if err != nil {
+			return fmt.Errorf("failed to create album folder %s: %w", albumPath, err)
+		}
+	}
+	d.QueueMgr.UpdateJobOutputPath(jobID, albumPath)
+
+	if !opts.DryRun {
+		d.writeSidecars(jobID, albumPath, meta, rawMeta, tmplData, tracks)
+	}
+
+	// Additional formats each get their own album folder
+	folders, err := d.extraFormatFolders(albumPath, func(format int) (string, error) {
+		return d.albumFolderPath(d.outPath(opts), tmplData.withQuality(&Quality{Format: format}))
+	}, opts)
 	if err != nil {
-		return fmt.Errorf("failed to create album folder %s: %w", albumPath, err)
+		return fmt.Errorf("failed to create additional format folders: %w", err)
 	}
 
+	layout := d.effectiveLayout(opts)
+	slots := layoutTracks(tracks, layout)
+	logger.Info("[processAlbum] Using track layout", "jobID", jobID, "layout", layout)
+
+	// Apply the track selection; layout numbering above still refers to the full release
+	selected, err := selectTracks(tracks, opts.Selection)
+	if err != nil {
+		return fmt.Errorf("invalid track selection: %w", err)
+	}
+	selectedTotal := 0
+	for _, sel := range selected {
+		if sel {
+			selectedTotal++
+		}
+	}
+	if selectedTotal == 0 {
+		return fmt.Errorf("track selection matched none of the %d tracks in release %s", trackTotal, albumID)
+	}
+	if selectedTotal < trackTotal {
+		logger.Info("[processAlbum] Downloading selected tracks only", "jobID", jobID, "selected", selectedTotal, "total", trackTotal)
+	}
+
+	var downloaded []api.TrackResult // Primary-format tracks, the sources for transcoding
+	var entries []showEntry          // Playlist/cue sheet entries, in track order
+	trackNum := 0
 	for i, track := range tracks {
+		if !selected[i] {
+			logger.Debug("[processAlbum] Skipping unselected track", "jobID", jobID, "trackIndex", i, "songTitle", track.SongTitle, "trackExclude", track.TrackExclude)
+			continue
+		}
 		logger.Debug("[processAlbum] Processing audio track from album",
 			"jobID", jobID,
 			"albumID", albumID,
 			"trackIndex", i,
 			"trackID", track.TrackID,
 			"songTitle", track.SongTitle)
-		trackNum := i + 1
-		err := d.processTrack(jobID, albumPath, trackNum, trackTotal, &track, streamParams)
+		trackNum++
+		trackFolders := make([]formatFolder, len(folders))
+		for j, f := range folders {
+			trackFolders[j] = formatFolder{Format: f.Format, Path: filepath.Join(f.Path, slots[i].SubDir)}
+		}
+		result, err := d.processTrack(jobID, trackFolders, trackNum, selectedTotal, &track, streamParams, tmplData.withSlot(&track, slots[i]), opts)
+		if result != nil {
+			downloaded = append(downloaded, *result)
+			entries = append(entries, showEntry{Path: result.Path, Title: track.SongTitle, Artist: meta.ArtistName, Seconds: track.TotalRunningTime, SetNum: track.SetNum, Written: !result.Existing})
+		}
 		if err != nil {
 			// Log error but continue with other tracks?
 			fmt.Printf("Error processing track %d (%s): %v\n", trackNum, track.SongTitle, err)
+			d.QueueMgr.AddJobTrackResult(jobID, api.TrackResult{TrackNum: trackNum, Title: track.SongTitle, Error: err.Error()})
 			// Optionally collect errors and return them at the end
 		}
 	}
+
+	if opts.DryRun {
+		d.planAlbumExtras(jobID, albumPath, tmplData, entries, downloaded, opts)
+		return nil
+	}
+
+	// Optionally render the whole release as one gapless file with chapters
+	singleFile := d.effectiveSingleFile(opts)
+	replaced := false
+	if singleFile != SingleFileOff && len(entries) > 0 {
+		name := filepath.Base(albumPath)
+		if singleFile == SingleFileAlongside {
+			name += singleFileSuffix
+		}
+		if _, err := d.renderSingleFile(jobID, albumPath, name, tmplData, entries); err != nil {
+			logger.Error("Failed to render release as a single file", "error", err, "jobID", jobID)
+		} else {
+			replaced = singleFile == SingleFileReplace
+		}
+	}
+	if !replaced {
+		d.writeShowFiles(jobID, albumPath, tmplData, entries)
+	}
+
+	if len(d.config().TranscodeProfiles) > 0 {
+		d.transcodeAlbum(jobID, albumPath, tmplData, downloaded, extractArtworkUrl(meta))
+	}
+	if replaced {
+		removeTrackFiles(jobID, albumPath, entries)
+	}
 	return nil // Or return collected errors
 }
 
@@ -580,12 +903,24 @@ func (d *Downloader) processArtist(jobID string, artistId string, opts DownloadO
 	}
 
 	fmt.Println("Artist:", containers[0].ArtistName) // Assuming first container has artist name
-	itemTotal := len(containers)
-	fmt.Printf("Found %d items for artist.\n", itemTotal)
+	fmt.Printf("Found %d items for artist.\n", len(containers))
 
 	// Update job title with the artist name
 	d.QueueMgr.UpdateJobTitle(jobID, containers[0].ArtistName)
 
+	// Narrow the releases down to those the job's filter picks
+	if opts.ArtistFilter != nil {
+		total := len(containers)
+		var inLibrary int
+		containers, inLibrary, err = d.filterArtistContainers(containers, opts)
+		if err != nil {
+			return fmt.Errorf("invalid artist filter: %w", err)
+		}
+		logger.Info("Filtered artist releases", "artistID", artistId, "total", total, "matched", len(containers), "inLibrary", inLibrary, "jobID", jobID)
+		opts = filterMediaOptions(opts)
+	}
+	itemTotal := len(containers)
+
 	var firstErr error // Variable to store the first error encountered
 
 	for i, containerMeta := range containers {
@@ -610,9 +945,9 @@ func (d *Downloader) processArtist(jobID string, artistId string, opts DownloadO
 
 // processPlaylist downloads all tracks for a playlist.
 // (Refactored from playlist in main.go)
-func (d *Downloader) processPlaylist(jobID string, plistId, legacyToken string, isCatalogPlist bool, streamParams *StreamParams) error {
+func (d *Downloader) processPlaylist(jobID string, plistId, legacyToken string, isCatalogPlist bool, opts DownloadOptions, streamParams *StreamParams) error {
 	// Playlist requires user email from config
-	email := d.Config.Email
+	email := d.config().Email
 	meta, err := d.getPlistMeta(plistId, email, legacyToken, isCatalogPlist)
 	if err != nil {
 		return fmt.Errorf("failed to get metadata for playlist %s: %w", plistId, err)
@@ -627,21 +962,51 @@ func (d *Downloader) processPlaylist(jobID string, plistId, legacyToken string,
 	// Update job title with the playlist name
 	d.QueueMgr.UpdateJobTitle(jobID, plistName)
 
-	plistPath := filepath.Join(d.Config.OutPath, SanitizeFilename(plistName))
-	err = MakeDirs(plistPath) // TODO: Move MakeDirs to utils
+	tmplData := PathTemplateData{PlaylistName: plistName}
+	plistPath, err := d.playlistFolderPath(d.outPath(opts), tmplData)
+	if err != nil {
+		return fmt.Errorf("failed to build playlist folder path: %w", err)
+	}
+	if !opts.DryRun {
+		err = MakeDirs(plistPath) // TODO: Move MakeDirs to utils
+		if err != nil {
+			return fmt.Errorf("failed to create playlist folder %s: %w", plistPath, err)
+		}
+	}
+	d.QueueMgr.UpdateJobOutputPath(jobID, plistPath)
+
+	// Additional formats each get their own playlist folder
+	folders, err := d.extraFormatFolders(plistPath, func(format int) (string, error) {
+		return d.playlistFolderPath(d.outPath(opts), tmplData.withQuality(&Quality{Format: format}))
+	}, opts)
 	if err != nil {
-		return fmt.Errorf("failed to create playlist folder %s: %w", plistPath, err)
+		return fmt.Errorf("failed to create additional format folders: %w", err)
 	}
 
 	trackTotal := len(meta.Response.Items)
+	var entries []showEntry // Playlist entries, in playlist order
 	for i, item := range meta.Response.Items {
 		trackNum := i + 1
-		err := d.processTrack(jobID, plistPath, trackNum, trackTotal, &item.Track, streamParams)
+		trackData := tmplData.withTrack(&item.Track, trackNum, trackTotal)
+		trackData.DiscNum, trackData.DiscTotal = 1, 1
+		result, err := d.processTrack(jobID, folders, trackNum, trackTotal, &item.Track, streamParams, trackData, opts)
+		if result != nil {
+			entries = append(entries, showEntry{Path: result.Path, Title: item.Track.SongTitle, Artist: playlistItemArtist(&item), Seconds: item.Track.TotalRunningTime, SetNum: item.Track.SetNum, Written: !result.Existing})
+		}
 		if err != nil {
 			fmt.Printf("Error processing track %d (%s) in playlist: %v\n", trackNum, item.Track.SongTitle, err)
+			d.QueueMgr.AddJobTrackResult(jobID, api.TrackResult{TrackNum: trackNum, Title: item.Track.SongTitle, Error: err.Error()})
 			// Optionally collect errors
 		}
 	}
+
+	if d.config().WriteM3U8 && len(entries) > 0 && !opts.DryRun {
+		if path, err := writeM3U8(plistPath, plistName, entries); err != nil {
+			logger.Warn("Failed to write playlist file", "error", err, "jobID", jobID)
+		} else {
+			logger.Info("Wrote playlist file", "path", path, "jobID", jobID)
+		}
+	}
 	return nil // Or return collected errors
 }
 
@@ -0,0 +1,62 @@
+package downloader
+
+import (
+	"reflect"
+	"testing"
+
+	appConfig "nugs-dl/internal/config"
+)
+
+func TestQualityChain(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  appConfig.AppConfig
+		opts DownloadOptions
+		want []int
+	}{
+		{"job list wins", appConfig.AppConfig{Format: 2, FormatFallback: []int{1}}, DownloadOptions{Formats: []int{3, 2}, Format: 1}, []int{3, 2}},
+		{"configured fallback", appConfig.AppConfig{Format: 1, FormatFallback: []int{2, 1}}, DownloadOptions{}, []int{2, 1}},
+		{"job format beats configured fallback", appConfig.AppConfig{FormatFallback: []int{2, 1}}, DownloadOptions{Format: 1}, []int{1, 2, 5}},
+		{"derived from config format", appConfig.AppConfig{Format: 2}, DownloadOptions{}, []int{2, 5}},
+		{"fallback to lossy", appConfig.AppConfig{Format: 1}, DownloadOptions{}, []int{1, 2, 5}},
+		{"strict stops before lossy", appConfig.AppConfig{Format: 1}, DownloadOptions{StrictQuality: true}, []int{1, 2}},
+		{"strict 360 keeps lossless steps", appConfig.AppConfig{Format: 4}, DownloadOptions{StrictQuality: true}, []int{4, 3, 2}},
+		{"fallback 360", appConfig.AppConfig{Format: 4}, DownloadOptions{}, []int{4, 3, 2, 5}},
+		{"strict lossy wanted", appConfig.AppConfig{Format: 5}, DownloadOptions{StrictQuality: true}, []int{5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			d := &Downloader{config: func() *appConfig.AppConfig { return &cfg }}
+			if got := d.qualityChain(tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("qualityChain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTrackQual(t *testing.T) {
+	flac := &Quality{Format: 2, Extension: ".flac"}
+	aac := &Quality{Format: 5, Extension: ".m4a"}
+	tests := []struct {
+		name   string
+		quals  []*Quality
+		chain  []int
+		strict bool
+		want   *Quality
+	}{
+		{"first in chain", []*Quality{aac, flac}, []int{2, 5}, false, flac},
+		{"falls back along chain", []*Quality{aac, flac}, []int{1, 2}, false, flac},
+		{"strict falls back within chain", []*Quality{aac}, []int{1, 2, 5}, true, aac},
+		{"strict fails outside chain", []*Quality{aac}, []int{1, 2}, true, nil},
+		{"fallback takes first available", []*Quality{aac, flac}, []int{1}, false, aac},
+		{"nothing available", nil, []int{2}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getTrackQual(tt.quals, tt.chain, tt.strict); got != tt.want {
+				t.Errorf("getTrackQual() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
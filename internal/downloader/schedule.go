@@ -0,0 +1,154 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"nugs-dl/internal/logger"
+	"nugs-dl/pkg/api"
+)
+
+// ErrScheduled is returned when a livestream job was parked until its event starts.
+// The worker leaves such jobs in 'scheduled' status instead of failing them.
+var ErrScheduled = errors.New("livestream capture scheduled")
+
+// Scheduled capture settings.
+const (
+	progressPhaseWaiting   = "waiting"
+	defaultLiveWaitMinutes = 60 // Retry a stream that isn't up yet this long past the start unless configured otherwise
+	liveWaitRetryInterval  = 30 * time.Second
+)
+
+// IsLiveCaptureJob reports whether a job records a livestream, which can wait for the
+// event and then record for hours. Dry runs only plan, so they don't count.
+func IsLiveCaptureJob(job *api.DownloadJob) bool {
+	if job.Options.DryRun {
+		return false
+	}
+	if job.ScheduledFor != nil {
+		return true
+	}
+	_, urlType := CheckUrl(job.OriginalUrl)
+	return urlType == ExclusiveLivestreamUrl || urlType == WatchExclusiveLivestreamUrl
+}
+
+// liveEventTimeLayouts are the date formats tried for live-event start times.
+var liveEventTimeLayouts = []string{layout, time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05"}
+
+// parseLiveEventTime parses a live-event time given as a date string or Unix timestamp.
+func parseLiveEventTime(v interface{}) (time.Time, bool) {
+	switch val := v.(type) {
+	case string:
+		val = strings.TrimSpace(val)
+		for _, l := range liveEventTimeLayouts {
+			if t, err := time.Parse(l, val); err == nil {
+				return t, true
+			}
+		}
+	case float64:
+		if val > 1e12 { // Milliseconds
+			return time.UnixMilli(int64(val)).UTC(), true
+		}
+		if val > 0 {
+			return time.Unix(int64(val), 0).UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// liveEventStart reads the event start time from the live-event info of a container's
+// product formats, preferring the live video product. Times without a zone are taken
+// as UTC, like the subscription dates.
+func liveEventStart(products []*ProductFormatList) (time.Time, bool) {
+	sorted := make([]*ProductFormatList, 0, len(products))
+	for _, p := range products {
+		if p != nil {
+			sorted = append(sorted, p)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].FormatStr == "LIVE HD VIDEO" && sorted[j].FormatStr != "LIVE HD VIDEO"
+	})
+	for _, p := range sorted {
+		event, ok := p.LiveEvent.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		keys := make([]string, 0, len(event))
+		for k := range event {
+			if strings.Contains(strings.ToLower(k), "start") {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if t, ok := parseLiveEventTime(event[k]); ok {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// liveWaitDuration returns how long past the event start a stream that isn't up is retried.
+func (d *Downloader) liveWaitDuration() time.Duration {
+	minutes := d.config().LiveWaitMinutes
+	if minutes <= 0 {
+		minutes = defaultLiveWaitMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// scheduleLivestream checks the start time of a livestream container. If the capture
+// is not due yet (start time minus the configured lead) the job is parked in 'scheduled'
+// status and ErrScheduled is returned. Otherwise opts.LiveWaitUntil is set so that a
+// stream that isn't up yet is retried until the wait timeout.
+func (d *Downloader) scheduleLivestream(jobID, containerID string, opts *DownloadOptions) error {
+	albumMeta, err := d.getAlbumMeta(containerID)
+	if err != nil {
+		return fmt.Errorf("failed to get metadata for livestream %s: %w", containerID, err)
+	}
+	if albumMeta.Response == nil {
+		return fmt.Errorf("API returned empty response for livestream %s", containerID)
+	}
+	start, ok := liveEventStart(albumMeta.Response.ProductFormatList)
+	if !ok {
+		logger.Info("No live event start time found, capturing right away", "jobID", jobID, "containerID", containerID)
+		return nil
+	}
+
+	captureAt := start.Add(-time.Duration(d.config().LiveLeadMinutes) * time.Minute)
+	if time.Now().Before(captureAt) {
+		d.QueueMgr.UpdateJobTitle(jobID, strings.TrimRight(albumMeta.Response.ContainerInfo, " "))
+		if artworkURL := extractArtworkUrl(albumMeta.Response); artworkURL != "" {
+			d.QueueMgr.UpdateJobArtwork(jobID, artworkURL)
+		}
+		d.QueueMgr.ScheduleJob(jobID, captureAt)
+		return fmt.Errorf("%w: event starts %s, capture begins %s", ErrScheduled, start.Format(time.RFC3339), captureAt.Format(time.RFC3339))
+	}
+	opts.LiveWaitUntil = start.Add(d.liveWaitDuration())
+	logger.Info("Livestream capture is due", "jobID", jobID, "eventStart", start, "retryUntil", opts.LiveWaitUntil)
+	return nil
+}
+
+// waitForStream calls fn until it succeeds or the deadline passes, for streams that
+// aren't up yet when a scheduled capture begins. A zero deadline calls fn once.
+func (d *Downloader) waitForStream(jobID string, deadline time.Time, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !time.Now().Add(liveWaitRetryInterval).Before(deadline) {
+			return err
+		}
+		logger.Info("Livestream not available yet, retrying", "jobID", jobID, "attempt", attempt, "retryUntil", deadline, "error", err)
+		d.sendProgress(api.ProgressUpdate{
+			JobID:      jobID,
+			Message:    fmt.Sprintf("Waiting for the stream to start (retrying until %s)", deadline.Local().Format("15:04")),
+			TotalBytes: -1,
+			Phase:      progressPhaseWaiting,
+		})
+		time.Sleep(liveWaitRetryInterval)
+	}
+}
@@ -0,0 +1,179 @@
+package downloader
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"nugs-dl/pkg/api"
+)
+
+// trackMatcher holds a parsed api.TrackSelection.
+type trackMatcher struct {
+	ranges          [][2]int // Inclusive 1-based index ranges
+	sets            map[int]bool
+	titles          []*regexp.Regexp
+	includeExcluded bool
+}
+
+// parseTrackSelection compiles a selection. A nil selection selects every
+// track that isn't marked as excluded in the metadata.
+func parseTrackSelection(sel *api.TrackSelection) (*trackMatcher, error) {
+	m := &trackMatcher{sets: make(map[int]bool)}
+	if sel == nil {
+		return m, nil
+	}
+	m.includeExcluded = sel.IncludeExcluded
+
+	for _, spec := range sel.Tracks {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(spec, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil || start < 1 {
+			return nil, fmt.Errorf("invalid track index %q", spec)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(strings.TrimSpace(to))
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid track range %q", spec)
+			}
+		}
+		m.ranges = append(m.ranges, [2]int{start, end})
+	}
+
+	for _, set := range sel.Sets {
+		if set < 1 {
+			return nil, fmt.Errorf("invalid set number %d", set)
+		}
+		m.sets[set] = true
+	}
+
+	for _, pattern := range sel.Titles {
+		if pattern == "" {
+			continue
+		}
+		// /.../ is a regular expression, anything else a plain substring
+		expr := regexp.QuoteMeta(pattern)
+		if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+			expr = pattern[1 : len(pattern)-1]
+		}
+		re, err := regexp.Compile("(?i)" + expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid title pattern %q: %w", pattern, err)
+		}
+		m.titles = append(m.titles, re)
+	}
+	return m, nil
+}
+
+// ValidateTrackSelection reports whether a selection can be parsed.
+func ValidateTrackSelection(sel *api.TrackSelection) error {
+	_, err := parseTrackSelection(sel)
+	return err
+}
+
+// hasCriteria reports whether any index, set or title criteria were given.
+func (m *trackMatcher) hasCriteria() bool {
+	return len(m.ranges) > 0 || len(m.sets) > 0 || len(m.titles) > 0
+}
+
+// matches reports whether the track at 1-based index is selected.
+// Criteria are combined as a union; excluded tracks are dropped unless requested.
+func (m *trackMatcher) matches(index int, track *Track) bool {
+	if track.TrackExclude != 0 && !m.includeExcluded {
+		return false
+	}
+	if !m.hasCriteria() {
+		return true
+	}
+	for _, r := range m.ranges {
+		if index >= r[0] && index <= r[1] {
+			return true
+		}
+	}
+	if m.sets[track.SetNum] {
+		return true
+	}
+	for _, re := range m.titles {
+		if re.MatchString(track.SongTitle) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectTracks returns which tracks of a release the selection picks, by position.
+func selectTracks(tracks []Track, sel *api.TrackSelection) ([]bool, error) {
+	m, err := parseTrackSelection(sel)
+	if err != nil {
+		return nil, err
+	}
+	selected := make([]bool, len(tracks))
+	for i := range tracks {
+		selected[i] = m.matches(i+1, &tracks[i])
+	}
+	return selected, nil
+}
+
+// PreviewTracks lists the tracks of a release and whether a selection picks them.
+// Numbering follows the configured track layout (or the given one).
+func (d *Downloader) PreviewTracks(containerID string, sel *api.TrackSelection, layout string) (*api.TrackListPreview, error) {
+	albumMeta, err := d.getAlbumMeta(containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata for container %s: %w", containerID, err)
+	}
+	if albumMeta.Response == nil {
+		return nil, fmt.Errorf("API returned empty response for container %s", containerID)
+	}
+	meta := albumMeta.Response
+
+	tracks := meta.Tracks
+	if len(tracks) == 0 {
+		tracks = meta.Songs
+	}
+	trackPreviews, selectedCount, err := d.previewTrackList(tracks, sel, layout)
+	if err != nil {
+		return nil, err
+	}
+	return &api.TrackListPreview{
+		ContainerID:   meta.ContainerID,
+		ArtistName:    meta.ArtistName,
+		Title:         strings.TrimRight(meta.ContainerInfo, " "),
+		SelectedCount: selectedCount,
+		Tracks:        trackPreviews,
+	}, nil
+}
+
+// previewTrackList describes a release's tracks, numbered by the track layout, and
+// counts how many the selection picks.
+func (d *Downloader) previewTrackList(tracks []Track, sel *api.TrackSelection, layout string) ([]api.TrackPreview, int, error) {
+	selected, err := selectTracks(tracks, sel)
+	if err != nil {
+		return nil, 0, err
+	}
+	slots := layoutTracks(tracks, d.effectiveLayout(DownloadOptions{Layout: layout}))
+
+	previews := make([]api.TrackPreview, 0, len(tracks))
+	selectedCount := 0
+	for i, t := range tracks {
+		previews = append(previews, api.TrackPreview{
+			Index:           i + 1,
+			Number:          slots[i].Number,
+			DiscNum:         t.DiscNum,
+			SetNum:          t.SetNum,
+			Title:           t.SongTitle,
+			DurationSeconds: t.TotalRunningTime,
+			Excluded:        t.TrackExclude != 0,
+			Selected:        selected[i],
+		})
+		if selected[i] {
+			selectedCount++
+		}
+	}
+	return previews, selectedCount, nil
+}
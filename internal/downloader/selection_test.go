@@ -0,0 +1,66 @@
+package downloader
+
+import (
+	"reflect"
+	"testing"
+
+	"nugs-dl/pkg/api"
+)
+
+func TestSelectTracks(t *testing.T) {
+	tracks := []Track{
+		{SongTitle: "Dark Star", SetNum: 1},
+		{SongTitle: "St. Stephen", SetNum: 1},
+		{SongTitle: "The Eleven", SetNum: 2},
+		{SongTitle: "Tuning", SetNum: 2, TrackExclude: 1},
+		{SongTitle: "Lovelight", SetNum: 2},
+	}
+	tests := []struct {
+		name string
+		sel  *api.TrackSelection
+		want []bool
+	}{
+		{"nil selects all but excluded", nil, []bool{true, true, true, false, true}},
+		{"single indices", &api.TrackSelection{Tracks: []string{"1", " 5 "}}, []bool{true, false, false, false, true}},
+		{"range", &api.TrackSelection{Tracks: []string{"2-3"}}, []bool{false, true, true, false, false}},
+		{"range skips excluded", &api.TrackSelection{Tracks: []string{"3-4"}}, []bool{false, false, true, false, false}},
+		{"range with excluded", &api.TrackSelection{Tracks: []string{"3-4"}, IncludeExcluded: true}, []bool{false, false, true, true, false}},
+		{"set", &api.TrackSelection{Sets: []int{2}}, []bool{false, false, true, false, true}},
+		{"title substring", &api.TrackSelection{Titles: []string{"STEPHEN"}}, []bool{false, true, false, false, false}},
+		{"title regex", &api.TrackSelection{Titles: []string{"/^(dark|the) /"}}, []bool{true, false, true, false, false}},
+		{"substring is literal", &api.TrackSelection{Titles: []string{"St.ephen", "."}}, []bool{false, true, false, false, false}},
+		{"union of criteria", &api.TrackSelection{Tracks: []string{"1"}, Titles: []string{"light"}}, []bool{true, false, false, false, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectTracks(tracks, tt.sel)
+			if err != nil {
+				t.Fatalf("selectTracks() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectTracks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrackSelectionErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sel  *api.TrackSelection
+	}{
+		{"zero index", &api.TrackSelection{Tracks: []string{"0"}}},
+		{"not a number", &api.TrackSelection{Tracks: []string{"x"}}},
+		{"reversed range", &api.TrackSelection{Tracks: []string{"5-3"}}},
+		{"open range", &api.TrackSelection{Tracks: []string{"2-"}}},
+		{"zero set", &api.TrackSelection{Sets: []int{0}}},
+		{"bad regex", &api.TrackSelection{Titles: []string{"/[a/"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseTrackSelection(tt.sel); err == nil {
+				t.Errorf("parseTrackSelection(%+v) succeeded, want error", tt.sel)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"nugs-dl/internal/logger"
+)
+
+// sessionTTL is how long a login is reused by format probes and previews.
+const sessionTTL = 30 * time.Minute
+
+// session holds what a logged-in user needs to access streams and user content.
+type session struct {
+	Token        string
+	UserID       string
+	LegacyToken  string // Needed for user playlists
+	LegacyUguid  string // Needed for purchased items
+	StreamParams *StreamParams
+
+	fetchedAt   time.Time
+	credentials string // Token or email the session was created with
+}
+
+// newSession authenticates with the configured token or email/password and fetches
+// the user and subscription details the stream API needs.
+func (d *Downloader) newSession() (*session, error) {
+	sess := &session{fetchedAt: time.Now(), credentials: d.sessionCredentials()}
+	var err error
+
+	// --- Authentication (Uses d.config()) ---
+	if d.config().Token != "" {
+		sess.Token = d.config().Token // Use provided token
+		logger.Info("Using provided auth token from config.")
+	} else if d.config().Email != "" && d.config().Password != "" {
+		// Authenticate with email/password
+		sess.Token, err = d.Authenticate(d.config().Email, d.config().Password)
+		if err != nil {
+			logger.Error("Authentication failed using email/password", "error", err)
+			return nil, fmt.Errorf("authentication failed: %w", err)
+		}
+		logger.Info("Successfully authenticated using email/password.")
+	} else {
+		logger.Error("Authentication required: email/password or token missing in config")
+		return nil, errors.New("authentication required: provide email/password or token in config")
+	}
+
+	// --- Get User Info & Subscription Details (Needed for StreamParams) ---
+	logger.Info("Fetching user and subscription info...")
+	sess.UserID, err = d.GetUserInfo(sess.Token)
+	if err != nil {
+		logger.Error("Failed to get user info", "error", err)
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+	subInfo, err := d.GetSubInfo(sess.Token)
+	if err != nil {
+		logger.Error("Failed to get subscription info", "error", err)
+		return nil, fmt.Errorf("failed to get subscription info: %w", err)
+	}
+
+	planDesc, _ := getPlan(subInfo) // Use internal getPlan
+	logger.Info("User subscription plan determined", "plan", planDesc)
+
+	// --- Extract Legacy Tokens (Needed for some playlist types AND purchased URLs) ---
+	sess.LegacyToken, sess.LegacyUguid, err = ExtractLegacyTokens(sess.Token)
+	if err != nil {
+		logger.Warn("Could not extract legacy tokens from main auth token", "error", err)
+	}
+
+	// --- Parse Stream Params (Needed for most downloads) ---
+	sess.StreamParams, err = ParseStreamParams(sess.UserID, subInfo)
+	if err != nil {
+		logger.Error("Failed to parse stream parameters", "error", err)
+		// This is likely fatal for most downloads
+		return nil, fmt.Errorf("failed to parse stream parameters: %w", err)
+	}
+	return sess, nil
+}
+
+// cachedSession returns the last session if it is younger than sessionTTL and was
+// created with the current credentials, and logs in again otherwise.
+func (d *Downloader) cachedSession() (*session, error) {
+	d.sessionMu.Lock()
+	defer d.sessionMu.Unlock()
+	if d.session != nil && time.Since(d.session.fetchedAt) < sessionTTL &&
+		d.session.credentials == d.sessionCredentials() {
+		return d.session, nil
+	}
+	sess, err := d.newSession()
+	if err != nil {
+		return nil, err
+	}
+	d.session = sess
+	return sess, nil
+}
+
+// sessionCredentials identifies the login the config asks for, so a cached session
+// isn't reused after the token or account changes.
+func (d *Downloader) sessionCredentials() string {
+	cfg := d.config()
+	if cfg.Token != "" {
+		return "token:" + cfg.Token
+	}
+	return "email:" + cfg.Email + ":" + cfg.Password
+}
@@ -0,0 +1,164 @@
+package downloader
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"nugs-dl/internal/logger"
+)
+
+// showEntry is a downloaded track listed in a show's playlist and cue sheet.
+type showEntry struct {
+	Path    string // Absolute path of the audio file
+	Title   string
+	Artist  string
+	Seconds int
+	SetNum  int
+	Written bool // Downloaded by this job rather than already present
+}
+
+// playlistItemArtist returns the artist of a playlist item from its container, if present.
+func playlistItemArtist(item *PlistItem) string {
+	if container, ok := item.PlaylistContainer.(map[string]interface{}); ok {
+		if name, ok := container["artistName"].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// relEntryPath returns the entry path relative to dir, using forward slashes as players expect.
+func relEntryPath(dir, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// writeM3U8 writes an extended UTF-8 playlist of entries into dir, in order.
+func writeM3U8(dir, name string, entries []showEntry) (string, error) {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	for _, e := range entries {
+		seconds := e.Seconds
+		if seconds <= 0 {
+			seconds = -1 // Unknown length
+		}
+		fmt.Fprintf(&b, "#EXTINF:%d,%s - %s\n", seconds, e.Artist, e.Title)
+		b.WriteString(relEntryPath(dir, e.Path) + "\n")
+	}
+
+	path := filepath.Join(dir, SanitizeFilename(name)+".m3u8")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write playlist %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// cueQuote makes a value safe for a double-quoted cue sheet field.
+func cueQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "'") + `"`
+}
+
+// writeCueHeader writes the show-level fields of a cue sheet.
+func writeCueHeader(b *strings.Builder, data PathTemplateData) {
+	fmt.Fprintf(b, "PERFORMER %s\n", cueQuote(data.ArtistName))
+	fmt.Fprintf(b, "TITLE %s\n", cueQuote(data.ContainerInfo))
+	if data.Date != "" {
+		fmt.Fprintf(b, "REM DATE %s\n", data.Date)
+	} else if data.Year != "" {
+		fmt.Fprintf(b, "REM DATE %s\n", data.Year)
+	}
+	if data.VenueName != "" {
+		fmt.Fprintf(b, "REM VENUE %s\n", cueQuote(data.VenueName))
+	}
+}
+
+// writeCue writes a multi-file cue sheet describing a show into dir.
+// A "REM SET n" marker precedes the first track of every set.
+func writeCue(dir, name string, data PathTemplateData, entries []showEntry) (string, error) {
+	var b strings.Builder
+	writeCueHeader(&b, data)
+
+	lastSet := 0
+	for i, e := range entries {
+		if e.SetNum > 0 && e.SetNum != lastSet {
+			fmt.Fprintf(&b, "REM SET %d\n", e.SetNum)
+			lastSet = e.SetNum
+		}
+		fmt.Fprintf(&b, "FILE %s WAVE\n", cueQuote(relEntryPath(dir, e.Path)))
+		fmt.Fprintf(&b, "  TRACK %02d AUDIO\n", i+1)
+		fmt.Fprintf(&b, "    TITLE %s\n", cueQuote(e.Title))
+		fmt.Fprintf(&b, "    PERFORMER %s\n", cueQuote(e.Artist))
+		b.WriteString("    INDEX 01 00:00:00\n")
+	}
+
+	path := filepath.Join(dir, SanitizeFilename(name)+".cue")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write cue sheet %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// cueTimestamp formats seconds as a cue sheet MM:SS:FF index, in frames of 1/75 s.
+func cueTimestamp(seconds float64) string {
+	frames := int(math.Round(seconds * 75))
+	return fmt.Sprintf("%02d:%02d:%02d", frames/(75*60), frames/75%60, frames%75)
+}
+
+// writeSingleFileCue writes a cue sheet for a show rendered as one audio file.
+// starts holds the offset in seconds of each entry within audioPath.
+func writeSingleFileCue(dir, name, audioPath string, data PathTemplateData, entries []showEntry, starts []float64) (string, error) {
+	var b strings.Builder
+	writeCueHeader(&b, data)
+	fileType := "WAVE"
+	if strings.EqualFold(filepath.Ext(audioPath), ".mp3") {
+		fileType = "MP3"
+	}
+	fmt.Fprintf(&b, "FILE %s %s\n", cueQuote(relEntryPath(dir, audioPath)), fileType)
+
+	lastSet := 0
+	for i, e := range entries {
+		if e.SetNum > 0 && e.SetNum != lastSet {
+			fmt.Fprintf(&b, "  REM SET %d\n", e.SetNum)
+			lastSet = e.SetNum
+		}
+		fmt.Fprintf(&b, "  TRACK %02d AUDIO\n", i+1)
+		fmt.Fprintf(&b, "    TITLE %s\n", cueQuote(e.Title))
+		fmt.Fprintf(&b, "    PERFORMER %s\n", cueQuote(e.Artist))
+		fmt.Fprintf(&b, "    INDEX 01 %s\n", cueTimestamp(starts[i]))
+	}
+
+	path := filepath.Join(dir, SanitizeFilename(name)+".cue")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write cue sheet %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// writeShowFiles writes the configured playlist and cue sheet for an album folder.
+// Failures are logged and don't fail the job.
+func (d *Downloader) writeShowFiles(jobID, albumPath string, data PathTemplateData, entries []showEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	name := filepath.Base(albumPath)
+	if d.config().WriteM3U8 {
+		if path, err := writeM3U8(albumPath, name, entries); err != nil {
+			logger.Warn("Failed to write album playlist", "error", err, "jobID", jobID)
+		} else {
+			logger.Info("Wrote album playlist", "path", path, "jobID", jobID)
+		}
+	}
+	if d.config().WriteCue {
+		if path, err := writeCue(albumPath, name, data, entries); err != nil {
+			logger.Warn("Failed to write cue sheet", "error", err, "jobID", jobID)
+		} else {
+			logger.Info("Wrote cue sheet", "path", path, "jobID", jobID)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package downloader
+
+import "testing"
+
+func TestCueTimestamp(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "00:00:00"},
+		{1, "00:01:00"},
+		{0.02, "00:00:02"},  // 1.5 frames rounds up
+		{0.006, "00:00:00"}, // 0.45 frames rounds down
+		{61.5, "01:01:38"},
+		{59.999, "01:00:00"}, // Rounding carries into the minutes
+		{3600, "60:00:00"},   // Minutes don't wrap into hours
+	}
+	for _, tt := range tests {
+		if got := cueTimestamp(tt.seconds); got != tt.want {
+			t.Errorf("cueTimestamp(%v) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
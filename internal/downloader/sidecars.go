@@ -0,0 +1,97 @@
+package downloader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"nugs-dl/internal/logger"
+)
+
+// Sidecar filenames written beside the music.
+const (
+	metadataSidecarFname = "nugs.json"
+	setlistSidecarFname  = "info.txt"
+)
+
+// formatRunningTime formats seconds as H:MM:SS, or M:SS for under an hour.
+func formatRunningTime(seconds int) string {
+	if seconds <= 0 {
+		return "?"
+	}
+	h, m, s := seconds/3600, seconds%3600/60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// buildSetlist renders a human-readable setlist for a container.
+func buildSetlist(meta *AlbArtResp, data PathTemplateData, tracks []Track) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s - %s\n\n", data.ArtistName, data.ContainerInfo)
+
+	var place []string
+	for _, p := range []string{meta.VenueName, meta.VenueCity, meta.VenueState} {
+		if p = strings.TrimSpace(p); p != "" {
+			place = append(place, p)
+		}
+	}
+	if len(place) > 0 {
+		fmt.Fprintf(&b, "Venue:        %s\n", strings.Join(place, ", "))
+	}
+	if data.Date != "" {
+		fmt.Fprintf(&b, "Date:         %s\n", data.Date)
+	} else if meta.PerformanceDate != "" {
+		fmt.Fprintf(&b, "Date:         %s\n", meta.PerformanceDate)
+	}
+	fmt.Fprintf(&b, "Running time: %s\n", formatRunningTime(meta.TotalContainerRunningTime))
+	fmt.Fprintf(&b, "Container ID: %d\n", meta.ContainerID)
+
+	lastSet := -1
+	for i, t := range tracks {
+		if t.SetNum != lastSet {
+			b.WriteString("\n")
+			if t.SetNum > 0 {
+				fmt.Fprintf(&b, "Set %d\n", t.SetNum)
+			}
+			lastSet = t.SetNum
+		}
+		fmt.Fprintf(&b, "  %02d. %s (%s)\n", i+1, t.SongTitle, formatRunningTime(t.TotalRunningTime))
+	}
+	return b.String()
+}
+
+// writeSidecars writes the configured metadata sidecars into albumPath.
+// raw is the container response as returned by the API; if empty, meta is marshalled instead.
+// Failures are logged and don't fail the job.
+func (d *Downloader) writeSidecars(jobID, albumPath string, meta *AlbArtResp, raw []byte, data PathTemplateData, tracks []Track) {
+	if d.config().WriteMetadataJSON {
+		var out bytes.Buffer
+		var err error
+		if len(raw) > 0 {
+			err = json.Indent(&out, raw, "", "  ")
+		} else {
+			var b []byte
+			b, err = json.MarshalIndent(meta, "", "  ")
+			out.Write(b)
+		}
+		if err == nil {
+			path := filepath.Join(albumPath, metadataSidecarFname)
+			err = os.WriteFile(path, out.Bytes(), 0644)
+		}
+		if err != nil {
+			logger.Warn("Failed to write metadata sidecar", "albumPath", albumPath, "error", err, "jobID", jobID)
+		}
+	}
+
+	if d.config().WriteSetlist {
+		path := filepath.Join(albumPath, setlistSidecarFname)
+		if err := os.WriteFile(path, []byte(buildSetlist(meta, data, tracks)), 0644); err != nil {
+			logger.Warn("Failed to write setlist sidecar", "albumPath", albumPath, "error", err, "jobID", jobID)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package downloader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"nugs-dl/internal/logger"
+	"nugs-dl/pkg/api"
+)
+
+// Single-file modes. They control whether a release is also rendered as one gapless file.
+const (
+	SingleFileOff       = ""          // Per-track files only (default)
+	SingleFileAlongside = "alongside" // One file with chapters next to the per-track files
+	SingleFileReplace   = "replace"   // One file with chapters instead of the per-track files
+)
+
+// singleFileSuffix is appended to the rendered file name in alongside mode,
+// so it doesn't collide with the per-track playlist and cue sheet.
+const singleFileSuffix = " - Full Show"
+
+// IsValidSingleFileMode reports whether mode is a known single-file mode.
+func IsValidSingleFileMode(mode string) bool {
+	switch mode {
+	case SingleFileOff, SingleFileAlongside, SingleFileReplace:
+		return true
+	}
+	return false
+}
+
+// concatListLine quotes a path for ffmpeg's concat demuxer list file.
+func concatListLine(path string) string {
+	return "file '" + strings.ReplaceAll(path, "'", `'\''`) + "'\n"
+}
+
+// renderSingleFile concatenates the downloaded tracks of a release into one file
+// with a chapter per song and writes a matching cue sheet. FLAC sources are
+// re-encoded to FLAC; ALAC and AAC sources are stream-copied into M4A.
+// It returns the path of the rendered file.
+func (d *Downloader) renderSingleFile(jobID, albumPath, name string, data PathTemplateData, entries []showEntry) (string, error) {
+	if len(entries) == 0 {
+		return "", errors.New("no tracks to concatenate")
+	}
+	ext := strings.ToLower(filepath.Ext(entries[0].Path))
+	for _, e := range entries[1:] {
+		if strings.ToLower(filepath.Ext(e.Path)) != ext {
+			return "", fmt.Errorf("tracks have mixed formats (%s and %s), cannot concatenate", ext, filepath.Ext(e.Path))
+		}
+	}
+	var codecArgs []string
+	switch ext {
+	case ".flac":
+		codecArgs = []string{"-c:a", "flac"}
+	case ".m4a":
+		codecArgs = []string{"-c:a", "copy"}
+	default:
+		return "", fmt.Errorf("single-file rendering is not supported for %s tracks", ext)
+	}
+
+	d.sendProgress(api.ProgressUpdate{
+		JobID:       jobID,
+		Message:     fmt.Sprintf("Rendering %d tracks into one file", len(entries)),
+		CurrentFile: name + ext,
+		Percentage:  100.0,
+	})
+
+	// Chapters are positioned from the actual track durations where ffmpeg can read them
+	var (
+		list   strings.Builder
+		marks  []chapterMark
+		starts []float64
+		offset float64
+	)
+	for _, e := range entries {
+		list.WriteString(concatListLine(e.Path))
+		dur, err := d.probeDuration(e.Path)
+		if err != nil || dur <= 0 {
+			logger.Warn("Could not read track duration, using metadata running time", "path", e.Path, "error", err, "jobID", jobID)
+			dur = float64(e.Seconds)
+		}
+		starts = append(starts, offset)
+		marks = append(marks, chapterMark{Title: e.Title, Start: offset, End: offset + dur})
+		offset += dur
+	}
+
+	listPath := filepath.Join(albumPath, ".concat_nugs_dl_tmp.txt")
+	metaPath := filepath.Join(albumPath, ".chapters_nugs_dl_tmp.txt")
+	defer os.Remove(listPath)
+	defer os.Remove(metaPath)
+	if err := os.WriteFile(listPath, []byte(list.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write concat list: %w", err)
+	}
+	if err := writeChapterMarks(metaPath, showTags(data), marks); err != nil {
+		return "", err
+	}
+
+	outPath := filepath.Join(albumPath, SanitizeFilename(name)+ext)
+	tmpPath := strings.TrimSuffix(outPath, ext) + ".concat" + ext
+	args := []string{"-hide_banner", "-f", "concat", "-safe", "0", "-i", listPath,
+		"-f", "ffmetadata", "-i", metaPath,
+		"-map", "0:a", "-map_metadata", "1", "-map_chapters", "1"}
+	args = append(args, codecArgs...)
+	args = append(args, "-y", tmpPath)
+
+	var errBuffer bytes.Buffer
+	cmd := exec.Command(d.getFfmpegCmd(), args...)
+	cmd.Stderr = &errBuffer
+	logger.Info("Executing FFmpeg concat command", "arguments", args, "jobID", jobID)
+	if err := cmd.Run(); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("ffmpeg concat failed: %w\nOutput:\n%s", err, errBuffer.String())
+	}
+	if err := os.Rename(tmpPath, outPath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to move concatenated file to %s: %w", outPath, err)
+	}
+
+	if _, err := writeSingleFileCue(albumPath, name, outPath, data, entries, starts); err != nil {
+		logger.Warn("Failed to write cue sheet for single file", "error", err, "jobID", jobID)
+	}
+	logger.Info("Rendered release as a single file", "path", outPath, "tracks", len(entries), "jobID", jobID)
+	return outPath, nil
+}
+
+// effectiveSingleFile picks the job single-file mode, then the configured one.
+func (d *Downloader) effectiveSingleFile(opts DownloadOptions) string {
+	if opts.SingleFile != "" {
+		return opts.SingleFile
+	}
+	return d.config().SingleFile
+}
+
+// removeTrackFiles deletes the per-track files this job wrote once they've been replaced
+// by a single file; files that were already there are kept. Subfolders beneath albumPath
+// left empty (e.g. disc folders) are removed too.
+func removeTrackFiles(jobID, albumPath string, entries []showEntry) {
+	for _, e := range entries {
+		if !e.Written {
+			logger.Debug("Keeping track file that predates the job", "path", e.Path, "jobID", jobID)
+			continue
+		}
+		if err := os.Remove(e.Path); err != nil {
+			logger.Warn("Failed to remove track file after rendering single file", "path", e.Path, "error", err, "jobID", jobID)
+		}
+	}
+	for _, e := range entries {
+		if dir := filepath.Dir(e.Path); dir != albumPath {
+			os.Remove(dir) // Only succeeds once empty
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Default path templates. These reproduce the layout used before templates were configurable.
+const (
+	defaultAlbumFolderTemplate    = `{{.ArtistName}} - {{.ContainerInfo}}`
+	defaultTrackFileTemplate      = `{{.Number}}. {{.SongTitle}}`
+	defaultPlaylistFolderTemplate = `{{.PlaylistName}}`
+	defaultVideoFileTemplate      = `{{.ArtistName}}/{{.ContainerInfo}}_{{.Resolution}}`
+)
+
+// Template kinds accepted by the preview endpoint.
+const (
+	TemplateKindAlbum    = "album"
+	TemplateKindTrack    = "track"
+	TemplateKindPlaylist = "playlist"
+	TemplateKindVideo    = "video"
+)
+
+// performanceDateLayouts lists the date formats seen in nugs.net container metadata.
+var performanceDateLayouts = []string{
+	"2006/01/02",
+	"2006-01-02",
+	"01/02/2006",
+	"1/2/2006",
+	"01/02/2006 15:04:05",
+}
+
+// templateFuncs are helpers available inside path templates.
+var templateFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	"pad": func(width, n int) string {
+		return fmt.Sprintf("%0*d", width, n)
+	},
+}
+
+// PathTemplateData holds the fields available to album, track, playlist and video templates.
+type PathTemplateData struct {
+	// Container fields
+	ArtistName    string
+	ContainerInfo string
+	ContainerID   int
+	ContainerType string
+	VenueName     string
+	VenueCity     string
+	VenueState    string
+
+	// Performance date parts (empty if the date could not be parsed)
+	Date  string // YYYY-MM-DD
+	Year  string
+	Month string
+	Day   string
+
+	// Track fields (positioned by the track layout mode)
+	TrackNum   int
+	TrackTotal int
+	DiscNum    int
+	DiscTotal  int
+	SetNum     int
+	Number     string // Layout-formatted number, e.g. "03", "d2t03", "s2t03"
+	SongTitle  string
+
+	// Format fields
+	Format     int
+	FormatName string
+	Specs      string
+	Extension  string
+
+	// Video fields
+	Resolution string
+
+	// Playlist fields
+	PlaylistName string
+}
+
+// formatNames maps format codes to short names for use in templates.
+var formatNames = map[int]string{
+	1: "ALAC",
+	2: "FLAC",
+	3: "MQA",
+	4: "360RA",
+	5: "AAC",
+	6: "AAC",
+}
+
+// parsePerformanceDate tries the known nugs.net date layouts.
+func parsePerformanceDate(dates ...string) (time.Time, bool) {
+	for _, d := range dates {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		for _, l := range performanceDateLayouts {
+			if t, err := time.Parse(l, d); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// newAlbumTemplateData builds template data from container metadata.
+func newAlbumTemplateData(meta *AlbArtResp) PathTemplateData {
+	data := PathTemplateData{}
+	if meta == nil {
+		return data
+	}
+	data.ArtistName = meta.ArtistName
+	data.ContainerInfo = strings.TrimRight(meta.ContainerInfo, " ")
+	data.ContainerID = meta.ContainerID
+	data.ContainerType = meta.ContainerTypeStr
+	data.VenueName = meta.VenueName
+	data.VenueCity = meta.VenueCity
+	data.VenueState = meta.VenueState
+	if t, ok := parsePerformanceDate(meta.PerformanceDateFormatted, meta.PerformanceDate); ok {
+		data.Date = t.Format("2006-01-02")
+		data.Year = t.Format("2006")
+		data.Month = t.Format("01")
+		data.Day = t.Format("02")
+	} else if meta.PerformanceDateYear != "" {
+		data.Year = meta.PerformanceDateYear
+	}
+	return data
+}
+
+// withTrack returns a copy of the data filled in with track fields.
+func (p PathTemplateData) withTrack(track *Track, trackNum, trackTotal int) PathTemplateData {
+	p.TrackNum = trackNum
+	p.TrackTotal = trackTotal
+	p.DiscNum = track.DiscNum
+	p.SetNum = track.SetNum
+	p.Number = fmt.Sprintf("%02d", trackNum)
+	p.SongTitle = track.SongTitle
+	return p
+}
+
+// withQuality returns a copy of the data filled in with format fields.
+func (p PathTemplateData) withQuality(q *Quality) PathTemplateData {
+	if q == nil {
+		return p
+	}
+	p.Format = q.Format
+	p.FormatName = formatNames[q.Format]
+	p.Specs = q.Specs
+	p.Extension = q.Extension
+	return p
+}
+
+// templateOrDefault returns tmpl, or def if tmpl is blank.
+func templateOrDefault(tmpl, def string) string {
+	if strings.TrimSpace(tmpl) == "" {
+		return def
+	}
+	return tmpl
+}
+
+// escaped returns a copy with '/' replaced in all free-text fields, so that only
+// separators written in the template itself create subdirectories.
+func (p PathTemplateData) escaped() PathTemplateData {
+	esc := func(s string) string { return strings.ReplaceAll(s, "/", "_") }
+	p.ArtistName = esc(p.ArtistName)
+	p.ContainerInfo = esc(p.ContainerInfo)
+	p.ContainerType = esc(p.ContainerType)
+	p.VenueName = esc(p.VenueName)
+	p.VenueCity = esc(p.VenueCity)
+	p.VenueState = esc(p.VenueState)
+	p.SongTitle = esc(p.SongTitle)
+	p.Specs = esc(p.Specs)
+	p.PlaylistName = esc(p.PlaylistName)
+	return p
+}
+
+// renderPathTemplate executes a path template and sanitizes each path segment.
+// A '/' in the template creates a subdirectory.
+func renderPathTemplate(tmplStr string, data PathTemplateData) (string, error) {
+	tmpl, err := template.New("path").Funcs(templateFuncs).Option("missingkey=error").Parse(tmplStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid path template %q: %w", tmplStr, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data.escaped()); err != nil {
+		return "", fmt.Errorf("failed to render path template %q: %w", tmplStr, err)
+	}
+
+	var segments []string
+	for _, seg := range strings.Split(buf.String(), "/") {
+		seg = SanitizeFilename(seg)
+		if seg == "" || seg == "." || seg == ".." {
+			continue
+		}
+		segments = append(segments, seg)
+	}
+	if len(segments) == 0 {
+		return "", fmt.Errorf("path template %q rendered an empty path", tmplStr)
+	}
+	return filepath.Join(segments...), nil
+}
+
+// ValidatePathTemplate checks that a template parses and renders against sample data.
+func ValidatePathTemplate(tmplStr string) error {
+	sample := PathTemplateData{
+		ArtistName:    "Artist",
+		ContainerInfo: "Venue",
+		ContainerID:   1,
+		Date:          "2000-01-01",
+		Year:          "2000",
+		Month:         "01",
+		Day:           "01",
+		TrackNum:      1,
+		TrackTotal:    1,
+		DiscNum:       1,
+		DiscTotal:     1,
+		SetNum:        1,
+		Number:        "01",
+		SongTitle:     "Song",
+		Format:        2,
+		FormatName:    "FLAC",
+		Extension:     ".flac",
+		Resolution:    "1080p",
+		PlaylistName:  "Playlist",
+	}
+	_, err := renderPathTemplate(tmplStr, sample)
+	return err
+}
+
+// albumFolderPath renders the album folder template beneath basePath.
+func (d *Downloader) albumFolderPath(basePath string, data PathTemplateData) (string, error) {
+	rel, err := renderPathTemplate(templateOrDefault(d.config().AlbumFolderTemplate, defaultAlbumFolderTemplate), data)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePath, rel), nil
+}
+
+// trackFileName renders the track filename template and appends the extension.
+func (d *Downloader) trackFileName(data PathTemplateData, extension string) (string, error) {
+	rel, err := renderPathTemplate(templateOrDefault(d.config().TrackFileTemplate, defaultTrackFileTemplate), data)
+	if err != nil {
+		return "", err
+	}
+	return rel + extension, nil
+}
+
+// playlistFolderPath renders the playlist folder template beneath basePath.
+func (d *Downloader) playlistFolderPath(basePath string, data PathTemplateData) (string, error) {
+	rel, err := renderPathTemplate(templateOrDefault(d.config().PlaylistFolderTemplate, defaultPlaylistFolderTemplate), data)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePath, rel), nil
+}
+
+// videoPathNoExt renders the video file template beneath basePath, without extension.
+func (d *Downloader) videoPathNoExt(basePath string, data PathTemplateData) (string, error) {
+	rel, err := renderPathTemplate(templateOrDefault(d.config().VideoFileTemplate, defaultVideoFileTemplate), data)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePath, rel), nil
+}
+
+// PreviewPathTemplate renders a template against the metadata of a real container.
+// For track templates every track of the release is rendered using the configured layout.
+func (d *Downloader) PreviewPathTemplate(kind, tmplStr, containerID string) ([]string, error) {
+	if _, err := strconv.Atoi(containerID); err != nil {
+		return nil, fmt.Errorf("invalid container ID %q", containerID)
+	}
+	albumMeta, err := d.getAlbumMeta(containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata for container %s: %w", containerID, err)
+	}
+	if albumMeta.Response == nil {
+		return nil, fmt.Errorf("API returned empty response for container %s", containerID)
+	}
+	meta := albumMeta.Response
+	data := newAlbumTemplateData(meta)
+
+	var tracks []Track
+	if len(meta.Tracks) > 0 {
+		tracks = meta.Tracks
+	} else if len(meta.Songs) > 0 {
+		tracks = meta.Songs
+	}
+	// Use the configured format's extension; the real one is only known after probing streams.
+	sampleQual := &Quality{Format: d.config().Format, Extension: ".flac"}
+	switch d.config().Format {
+	case 1, 5:
+		sampleQual.Extension = ".m4a"
+	case 4:
+		sampleQual.Extension = ".mp4"
+	}
+	data = data.withQuality(sampleQual)
+
+	var paths []string
+	switch kind {
+	case TemplateKindAlbum:
+		p, err := renderPathTemplate(tmplStr, data)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	case TemplateKindTrack:
+		slots := layoutTracks(tracks, d.effectiveLayout(DownloadOptions{}))
+		for i := range tracks {
+			p, err := renderPathTemplate(tmplStr, data.withSlot(&tracks[i], slots[i]))
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, filepath.Join(slots[i].SubDir, p+sampleQual.Extension))
+		}
+	case TemplateKindPlaylist:
+		data.PlaylistName = data.ArtistName + " - " + data.ContainerInfo
+		p, err := renderPathTemplate(tmplStr, data)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	case TemplateKindVideo:
+		data.Resolution = formatRes(resolveRes[d.config().VideoFormat])
+		p, err := renderPathTemplate(tmplStr, data)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, p+".mp4")
+	default:
+		return nil, fmt.Errorf("unknown template kind %q", kind)
+	}
+	return paths, nil
+}
@@ -0,0 +1,60 @@
+package downloader
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderPathTemplate(t *testing.T) {
+	data := PathTemplateData{
+		ArtistName:    "AC/DC",
+		ContainerInfo: "Live 1979/08/01",
+		VenueName:     "Hammersmith: Odeon",
+		SongTitle:     "Help/Slipknot!",
+		Number:        "03",
+		Year:          "1979",
+		TrackNum:      3,
+	}
+	tests := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{"default album folder", defaultAlbumFolderTemplate, "AC_DC - Live 1979_08_01"},
+		{"default track file", defaultTrackFileTemplate, "03. Help_Slipknot!"},
+		{"template separator makes folders", "{{.ArtistName}}/{{.Year}}", filepath.Join("AC_DC", "1979")},
+		{"sanitized characters", "{{.VenueName}}", "Hammersmith_ Odeon"},
+		{"empty segments dropped", "{{.ArtistName}}//{{.Month}}/{{.Year}}", filepath.Join("AC_DC", "1979")},
+		{"dot segments dropped", "../{{.Year}}/./x", filepath.Join("1979", "x")},
+		{"template funcs", "{{pad 3 .TrackNum}} {{lower .ArtistName}}", "003 ac_dc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderPathTemplate(tt.tmpl, data)
+			if err != nil {
+				t.Fatalf("renderPathTemplate(%q) error = %v", tt.tmpl, err)
+			}
+			if got != tt.want {
+				t.Errorf("renderPathTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderPathTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+	}{
+		{"parse error", "{{.ArtistName"},
+		{"unknown field", "{{.Nope}}"},
+		{"empty result", "{{.Month}}/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := renderPathTemplate(tt.tmpl, PathTemplateData{ArtistName: "A"}); err == nil {
+				t.Errorf("renderPathTemplate(%q) = %q, want error", tt.tmpl, got)
+			}
+		})
+	}
+}
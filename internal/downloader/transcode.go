@@ -0,0 +1,259 @@
+package downloader
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/jpeg" // Registered for reading cover dimensions
+	_ "image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	appConfig "nugs-dl/internal/config"
+	"nugs-dl/internal/logger"
+	"nugs-dl/pkg/api"
+)
+
+// progressPhaseTranscode marks progress updates sent while transcoding.
+const progressPhaseTranscode = "transcode"
+
+// transcodeSourceFormats are the formats transcoded when a profile doesn't list its own.
+var transcodeSourceFormats = []int{1, 2, 3}
+
+// wantsSource reports whether a profile transcodes tracks of the given format.
+func wantsSource(profile appConfig.TranscodeProfile, format int) bool {
+	formats := profile.SourceFormats
+	if len(formats) == 0 {
+		formats = transcodeSourceFormats
+	}
+	return formatInChain(formats, format)
+}
+
+// transcodeFolderPath renders the profile's album folder beneath its output root.
+func (d *Downloader) transcodeFolderPath(profile appConfig.TranscodeProfile, data PathTemplateData) (string, error) {
+	data.Format = 0
+	data.FormatName = profile.Name
+	data.Specs = ""
+	data.Extension = profile.Extension
+	tmpl := templateOrDefault(profile.FolderTemplate, templateOrDefault(d.config().AlbumFolderTemplate, defaultAlbumFolderTemplate))
+	rel, err := renderPathTemplate(tmpl, data)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(profile.OutPath, rel), nil
+}
+
+// transcodeOutPath mirrors a source track's place beneath albumPath into outDir.
+func transcodeOutPath(albumPath, outDir, srcPath, ext string) string {
+	rel, err := filepath.Rel(albumPath, srcPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(srcPath)
+	}
+	return filepath.Join(outDir, strings.TrimSuffix(rel, filepath.Ext(rel))+ext)
+}
+
+// fetchCover downloads a release's artwork into a temporary folder for embedding.
+// It returns the file path, empty if there is no artwork, and a cleanup function.
+func (d *Downloader) fetchCover(jobID, artworkURL string) (string, func()) {
+	if artworkURL == "" {
+		return "", func() {}
+	}
+	dir, err := os.MkdirTemp("", "nugs-cover-")
+	if err != nil {
+		logger.Warn("Failed to create folder for cover art", "error", err, "jobID", jobID)
+		return "", func() {}
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	coverPath := filepath.Join(dir, "cover"+filepath.Ext(strings.SplitN(artworkURL, "?", 2)[0]))
+	if err := d.fetchImage(artworkURL, coverPath); err != nil {
+		logger.Warn("Failed to fetch cover art for transcodes", "url", artworkURL, "error", err, "jobID", jobID)
+		cleanup()
+		return "", func() {}
+	}
+	return coverPath, cleanup
+}
+
+// pictureBlock encodes an image as a base64 FLAC picture block, the form Ogg
+// containers carry cover art in as a METADATA_BLOCK_PICTURE comment.
+func pictureBlock(coverPath string) (string, error) {
+	data, err := os.ReadFile(coverPath)
+	if err != nil {
+		return "", err
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to read cover image: %w", err)
+	}
+	mime := "image/" + format
+
+	var buf bytes.Buffer
+	put := func(v uint32) { binary.Write(&buf, binary.BigEndian, v) }
+	put(3) // Front cover
+	put(uint32(len(mime)))
+	buf.WriteString(mime)
+	put(0) // No description
+	put(uint32(cfg.Width))
+	put(uint32(cfg.Height))
+	put(24) // Colour depth
+	put(0)  // Not indexed
+	put(uint32(len(data)))
+	buf.Write(data)
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// transcodeAlbum runs every configured transcode profile over the tracks downloaded
+// into albumPath, embedding the release artwork. Failures are logged and don't fail the job.
+func (d *Downloader) transcodeAlbum(jobID, albumPath string, data PathTemplateData, tracks []api.TrackResult, artworkURL string) {
+	coverPath, cleanup := d.fetchCover(jobID, artworkURL)
+	defer cleanup()
+
+	for _, profile := range d.config().TranscodeProfiles {
+		var sources []api.TrackResult
+		for _, t := range tracks {
+			if t.Path != "" && wantsSource(profile, t.Format) {
+				sources = append(sources, t)
+			}
+		}
+		if len(sources) == 0 {
+			logger.Info("No tracks to transcode for profile", "profile", profile.Name, "jobID", jobID)
+			continue
+		}
+
+		outDir, err := d.transcodeFolderPath(profile, data)
+		if err != nil {
+			logger.Error("Failed to build transcode folder path", "profile", profile.Name, "error", err, "jobID", jobID)
+			continue
+		}
+		logger.Info("Transcoding album", "profile", profile.Name, "tracks", len(sources), "outDir", outDir, "jobID", jobID)
+
+		for i, src := range sources {
+			outPath := transcodeOutPath(albumPath, outDir, src.Path, profile.Extension)
+
+			d.sendProgress(api.ProgressUpdate{
+				JobID:        jobID,
+				Message:      fmt.Sprintf("Transcoding track %d/%d (%s)", i+1, len(sources), profile.Name),
+				CurrentFile:  filepath.Base(outPath),
+				Percentage:   float64(i) / float64(len(sources)) * 100.0,
+				CurrentTrack: i + 1,
+				TotalTracks:  len(sources),
+				Phase:        progressPhaseTranscode,
+			})
+
+			exists, err := FileExists(outPath)
+			if err == nil && exists {
+				logger.Info("Transcoded track already exists, skipping", "path", outPath, "profile", profile.Name, "jobID", jobID)
+				continue
+			}
+			if err := MakeDirs(filepath.Dir(outPath)); err != nil {
+				logger.Error("Failed to create transcode folder", "path", filepath.Dir(outPath), "error", err, "jobID", jobID)
+				continue
+			}
+			if err := d.transcodeFile(src.Path, outPath, coverPath, profile); err != nil {
+				logger.Error("Transcode failed", "source", src.Path, "profile", profile.Name, "error", err, "jobID", jobID)
+				continue
+			}
+		}
+
+		d.sendProgress(api.ProgressUpdate{
+			JobID:        jobID,
+			Message:      fmt.Sprintf("Finished transcoding (%s)", profile.Name),
+			Percentage:   100.0,
+			CurrentTrack: len(sources),
+			TotalTracks:  len(sources),
+			Phase:        progressPhaseTranscode,
+		})
+	}
+}
+
+// transcodeFile encodes one audio file with a profile. Tags are copied from the source
+// and the cover image at coverPath (or the source's embedded one) is attached; Ogg
+// outputs get it as a METADATA_BLOCK_PICTURE comment since they can't hold a picture stream.
+func (d *Downloader) transcodeFile(srcPath, outPath, coverPath string, profile appConfig.TranscodeProfile) error {
+	tmpPath := strings.TrimSuffix(outPath, profile.Extension) + ".transcoding" + profile.Extension
+
+	args := []string{"-hide_banner", "-i", srcPath}
+	switch strings.ToLower(profile.Extension) {
+	case ".opus", ".ogg":
+		metaPath, err := d.coverMetadataFile(srcPath, tmpPath, coverPath)
+		if err != nil {
+			logger.Warn("Failed to prepare cover art for Ogg output, transcoding without it", "source", srcPath, "error", err)
+		}
+		if metaPath != "" {
+			defer os.Remove(metaPath)
+			args = append(args, "-f", "ffmetadata", "-i", metaPath, "-map", "0:a", "-map_metadata", "1")
+		} else {
+			args = append(args, "-map", "0:a", "-map_metadata", "0")
+		}
+	default:
+		if coverPath != "" {
+			args = append(args, "-i", coverPath, "-map", "0:a", "-map", "1:v")
+		} else {
+			args = append(args, "-map", "0:a", "-map", "0:v?")
+		}
+		args = append(args, "-map_metadata", "0", "-c:v", "copy", "-disposition:v", "attached_pic")
+	}
+	args = append(args, "-c:a", profile.Codec)
+	if profile.Bitrate != "" {
+		args = append(args, "-b:a", profile.Bitrate)
+	}
+	if profile.Quality != "" {
+		args = append(args, "-q:a", profile.Quality)
+	}
+	if strings.EqualFold(profile.Extension, ".mp3") {
+		args = append(args, "-id3v2_version", "3")
+	}
+	args = append(args, "-y", tmpPath)
+
+	var errBuffer bytes.Buffer
+	cmd := exec.Command(d.getFfmpegCmd(), args...)
+	cmd.Stderr = &errBuffer
+	logger.Debug("Executing FFmpeg transcode command", "arguments", args)
+	if err := cmd.Run(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("ffmpeg transcode failed: %w\nOutput:\n%s", err, errBuffer.String())
+	}
+	if err := os.Rename(tmpPath, outPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to move transcoded file to %s: %w", outPath, err)
+	}
+	return nil
+}
+
+// coverMetadataFile writes the source's tags plus the cover as a METADATA_BLOCK_PICTURE
+// comment to an FFMETADATA file next to tmpPath. The picture is too large to pass on
+// the command line. It returns an empty path if there is no cover.
+func (d *Downloader) coverMetadataFile(srcPath, tmpPath, coverPath string) (string, error) {
+	if coverPath == "" {
+		return "", nil
+	}
+	block, err := pictureBlock(coverPath)
+	if err != nil {
+		return "", err
+	}
+	metaPath := tmpPath + ".ffmeta"
+	var errBuffer bytes.Buffer
+	cmd := exec.Command(d.getFfmpegCmd(), "-hide_banner", "-i", srcPath, "-f", "ffmetadata", "-y", metaPath)
+	cmd.Stderr = &errBuffer
+	if err := cmd.Run(); err != nil {
+		os.Remove(metaPath)
+		return "", fmt.Errorf("failed to read source tags: %w\nOutput:\n%s", err, errBuffer.String())
+	}
+	f, err := os.OpenFile(metaPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		os.Remove(metaPath)
+		return "", err
+	}
+	_, err = f.WriteString("METADATA_BLOCK_PICTURE=" + escapeFfmetadata(block) + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(metaPath)
+		return "", err
+	}
+	return metaPath, nil
+}
@@ -47,13 +47,6 @@ var resolveRes = map[int]string{
 	5: "2160", // Represents 4K
 }
 
-// resFallback defines the resolution fallback order if the desired one isn't available.
-var resFallback = map[string]string{
-	"720":  "480",
-	"1080": "720",
-	"1440": "1080",
-	// 4K (2160) doesn't have a defined fallback in original code, assumes highest available?
-}
 
 // --- Structs moved from main/structs.go ---
 
@@ -303,6 +296,7 @@ type AlbArtResp struct {
 	ContainerID               int                  `json:"containerID"`
 	ContainerInfo             string               `json:"containerInfo"`
 	ArtistName                string               `json:"artistName"`
+	ArtistID                  int                  `json:"artistID"`
 	AvailabilityTypeStr       string               `json:"availabilityTypeStr"`
 	ContainerTypeStr          string               `json:"containerTypeStr"`
 	ProductFormatList         []*ProductFormatList `json:"productFormatList"`
@@ -314,6 +308,11 @@ type AlbArtResp struct {
 	Pics            []ImageInfo `json:"pics"`
 	VenueName       string      `json:"venueName"`       // Added for context
 	PerformanceDate string      `json:"performanceDate"` // Added for context
+	// Fields used by path templates
+	PerformanceDateFormatted string `json:"performanceDateFormatted"` // e.g. "2023/10/31"
+	PerformanceDateYear      string `json:"performanceDateYear"`
+	VenueCity                string `json:"venueCity"`
+	VenueState               string `json:"venueState"`
 	// Simplified other fields
 }
 
@@ -334,6 +333,7 @@ type AlbumMeta struct {
 	ResponseAvailabilityCode    int         `json:"responseAvailabilityCode"`
 	ResponseAvailabilityCodeStr string      `json:"responseAvailabilityCodeStr"`
 	Response                    *AlbArtResp `json:"Response"`
+	Raw                         []byte      `json:"-"` // Undecoded response body
 }
 
 // PlistItem represents a single item within a playlist.
@@ -9,7 +9,6 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
-	"sort"
 	"strings"
 	"time"
 
@@ -21,17 +20,6 @@ import (
 
 // --- Video Processing Functions ---
 
-// getVidVariant selects the video variant matching the desired resolution.
-// (Moved from main.go)
-func getVidVariant(variants []*m3u8.Variant, wantRes string) *m3u8.Variant {
-	for _, variant := range variants {
-		if strings.HasSuffix(variant.Resolution, "x"+wantRes) {
-			return variant
-		}
-	}
-	return nil
-}
-
 // formatRes converts resolution string (e.g., "1080") to display format (e.g., "1080p", "4K").
 // (Moved from main.go)
 func formatRes(res string) string {
@@ -42,91 +30,41 @@ func formatRes(res string) string {
 	}
 }
 
-// chooseVariant parses the master video manifest, selects the best matching variant based
-// on desired resolution and fallbacks, and returns the chosen variant and its resolution.
+// chooseVariant parses the master video manifest and selects the variant that best
+// matches the preference, judged by parsed resolution, codec, frame rate and bandwidth.
 // (Moved from main.go)
-func (d *Downloader) chooseVariant(manifestUrl, wantRes string) (*m3u8.Variant, string, error) {
-	origWantRes := wantRes
-	var wantVariant *m3u8.Variant
-
+func (d *Downloader) chooseVariant(manifestUrl string, pref videoPreference) (variantInfo, error) {
 	req, err := d.HTTPClient.Get(manifestUrl)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to GET video master manifest %s: %w", manifestUrl, err)
+		return variantInfo{}, fmt.Errorf("failed to GET video master manifest %s: %w", manifestUrl, err)
 	}
 	defer req.Body.Close()
 	if req.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("bad status for video master manifest %s: %s", manifestUrl, req.Status)
+		return variantInfo{}, fmt.Errorf("bad status for video master manifest %s: %s", manifestUrl, req.Status)
 	}
 
 	playlist, listType, err := m3u8.DecodeFrom(req.Body, true)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to decode video master manifest %s: %w", manifestUrl, err)
+		return variantInfo{}, fmt.Errorf("failed to decode video master manifest %s: %w", manifestUrl, err)
 	}
 	if listType != m3u8.MASTER {
-		return nil, "", fmt.Errorf("expected video master playlist but got media playlist for %s", manifestUrl)
+		return variantInfo{}, fmt.Errorf("expected video master playlist but got media playlist for %s", manifestUrl)
 	}
 
 	master := playlist.(*m3u8.MasterPlaylist)
 	if len(master.Variants) == 0 {
-		return nil, "", fmt.Errorf("video master playlist %s contains no variants", manifestUrl)
-	}
-
-	// Sort by bandwidth DESC (highest first) to prefer higher quality if resolutions match
-	sort.Slice(master.Variants, func(x, y int) bool {
-		return master.Variants[x].Bandwidth > master.Variants[y].Bandwidth
-	})
-
-	// Handle 4K/Best Available request (originally used `resolveRes[5]` which is "2160")
-	if wantRes == "2160" {
-		bestVariant := master.Variants[0] // Highest bandwidth variant
-		// Extract resolution from the best variant found
-		if bestVariant.Resolution != "" {
-			parts := strings.SplitN(bestVariant.Resolution, "x", 2)
-			if len(parts) == 2 {
-				actualRes := parts[1]
-				logger.Info("Highest available video resolution determined from manifest", "resolution", actualRes+"p", "variantURI", bestVariant.URI)
-				return bestVariant, formatRes(actualRes), nil
-			}
-		}
-		// Fallback if resolution couldn't be parsed from best variant
-		logger.Warn("Could not determine resolution from highest bandwidth variant, falling back to default selection logic.", "variantURI", bestVariant.URI)
-		// Continue to normal selection logic below
-	}
-
-	// Find the desired resolution or fallback
-	currentTryRes := wantRes
-	for {
-		wantVariant = getVidVariant(master.Variants, currentTryRes)
-		if wantVariant != nil {
-			break // Found it
-		}
-		// Try fallback resolution
-		fbRes, hasFallback := resFallback[currentTryRes]
-		if !hasFallback {
-			break // No more fallbacks to try
-		}
-		logger.Info("Desired video resolution unavailable, falling back", "wanted", formatRes(currentTryRes), "fallbackTo", formatRes(fbRes))
-		currentTryRes = fbRes
+		return variantInfo{}, fmt.Errorf("video master playlist %s contains no variants", manifestUrl)
 	}
 
-	if wantVariant == nil {
-		// If no desired or fallback resolution found, maybe pick the best available?
-		logger.Info("Desired/fallback video resolutions not found, selecting highest available variant by bandwidth.", "selectedVariantURI", master.Variants[0].URI)
-		wantVariant = master.Variants[0]
-		parts := strings.SplitN(wantVariant.Resolution, "x", 2)
-		if len(parts) == 2 {
-			currentTryRes = parts[1]
-		} else {
-			return nil, "", fmt.Errorf("failed to find any suitable video variant and could not determine resolution of best variant")
-		}
+	chosen, err := selectVariant(master.Variants, pref)
+	if err != nil {
+		return variantInfo{}, fmt.Errorf("video master playlist %s: %w", manifestUrl, err)
 	}
-
-	finalResStr := formatRes(currentTryRes)
-	if currentTryRes != origWantRes && origWantRes != "2160" { // Don't print if 'best' was requested
-		logger.Info("Selected video resolution differs from original request due to availability/fallback", "originalWanted", formatRes(origWantRes), "selected", finalResStr)
+	if pref.MaxHeight > 0 && chosen.Height != pref.MaxHeight {
+		logger.Info("Selected video resolution differs from the requested one due to availability", "wanted", formatRes(strconv.Itoa(pref.MaxHeight)), "selected", chosen.resolutionLabel())
 	}
-
-	return wantVariant, finalResStr, nil
+	logger.Info("Selected video variant", "resolution", chosen.resolutionLabel(), "codec", chosen.Codec, "frameRate", chosen.FrameRate, "bandwidth", chosen.Bandwidth, "variantURI", chosen.Variant.URI)
+	return chosen, nil
 }
 
 // downloadVideoSegments downloads HLS video segments sequentially to a single file.
@@ -240,8 +178,8 @@ func (d *Downloader) processVideo(jobID, videoID, uguID string, opts DownloadOpt
 		"opts.SkipVideos", opts.SkipVideos,
 		"opts.SkipChapters", opts.SkipChapters,
 		"isLstream", isLstream,
-		"config.VideoFormat", d.Config.VideoFormat,
-		"config.LiveVideoPath", d.Config.LiveVideoPath,
+		"config.VideoFormat", d.config().VideoFormat,
+		"config.LiveVideoPath", d.config().LiveVideoPath,
 		"preloadedMeta_IsNil", preloadedMeta == nil,
 		"preloadedMeta.ContainerInfo", func() string {
 			if preloadedMeta == nil {
@@ -275,6 +213,7 @@ func (d *Downloader) processVideo(jobID, videoID, uguID string, opts DownloadOpt
 		}
 		meta = albumMeta.Response
 	}
+	d.applyArtistOverrides(jobID, meta, &opts)
 
 	// Extract and update artwork URL
 	artworkURL := extractArtworkUrl(meta) // Use helper defined in processing.go
@@ -294,30 +233,39 @@ func (d *Downloader) processVideo(jobID, videoID, uguID string, opts DownloadOpt
 	if isLstream {
 		skuID = getLstreamSku(meta.ProductFormatList)
 	} else {
-		skuID = getVideoSkuID(meta, d.Config.VideoFormat, jobID) // Corrected function call
+		skuID = getVideoSkuID(meta, d.videoFormat(opts), jobID) // Corrected function call
 	}
 	if skuID == 0 {
 		return errors.New("no suitable video product SKU found in metadata")
 	}
 
-	if uguID != "" { // Purchased video
-		manifestUrl, err = d.getPurchasedManUrl(skuID, videoID, streamParams.UserID, uguID)
-	} else { // Streamed video (requires subscription params)
-		manifestUrl, err = d.getStreamMeta(meta.ContainerID, skuID, 0, streamParams)
-	}
-	if err != nil {
-		return fmt.Errorf("failed to get video manifest URL: %w", err)
-	}
-	if manifestUrl == "" {
-		return errors.New("API returned an empty video manifest URL")
-	}
-
 	// --- Choose Variant (Resolution) ---
-	wantRes := resolveRes[d.Config.VideoFormat]
-	variant, chosenResStr, err := d.chooseVariant(manifestUrl, wantRes)
+	// A scheduled livestream may not be up yet, so this is retried until opts.LiveWaitUntil
+	var chosen variantInfo
+	err = d.waitForStream(jobID, opts.LiveWaitUntil, func() error {
+		var err error
+		if uguID != "" { // Purchased video
+			manifestUrl, err = d.getPurchasedManUrl(skuID, videoID, streamParams.UserID, uguID)
+		} else { // Streamed video (requires subscription params)
+			manifestUrl, err = d.getStreamMeta(meta.ContainerID, skuID, 0, streamParams)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get video manifest URL: %w", err)
+		}
+		if manifestUrl == "" {
+			return errors.New("API returned an empty video manifest URL")
+		}
+		chosen, err = d.chooseVariant(manifestUrl, d.videoPreferenceFor(d.videoFormat(opts)))
+		if err != nil {
+			return fmt.Errorf("failed to choose video variant: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to choose video variant: %w", err)
+		return err
 	}
+	variant, chosenResStr := chosen.Variant, chosen.resolutionLabel()
+	d.QueueMgr.UpdateJobVideoVariant(jobID, chosen.toAPI())
 	logger.Debug("[processVideo] Chosen Video Variant",
 		"jobID", jobID,
 		"videoID", videoID,
@@ -338,20 +286,30 @@ func (d *Downloader) processVideo(jobID, videoID, uguID string, opts DownloadOpt
 	d.QueueMgr.UpdateJobTitle(jobID, videoFnameBase)
 
 	// Determine the base path for the video download
-	videoBasePath := d.Config.OutPath // Default to the main output path
-	if d.Config.LiveVideoPath != "" {
-		videoBasePath = d.Config.LiveVideoPath // Use the specific path if provided
-	}
+	videoBasePath := d.videoOutPath(opts)
 
-	// Create artist-specific directory
-	artistPath := filepath.Join(videoBasePath, SanitizeFilename(meta.ArtistName))
-	if err := os.MkdirAll(artistPath, 0755); err != nil {
-		return fmt.Errorf("failed to create artist directory for video %s: %w", artistPath, err)
+	// Render the video path from the configured template
+	tmplData := newAlbumTemplateData(meta)
+	tmplData.Resolution = chosenResStr
+	vidPathNoExt, err := d.videoPathNoExt(videoBasePath, tmplData)
+	if err != nil {
+		return fmt.Errorf("failed to build video path: %w", err)
 	}
-
-	vidPathNoExt := filepath.Join(artistPath, SanitizeFilename(videoFnameBase+"_"+chosenResStr))
+	videoDir := filepath.Dir(vidPathNoExt)
 	vidPathTs := vidPathNoExt + ".ts"   // Path for raw downloaded segments
 	vidPathMp4 := vidPathNoExt + ".mp4" // Final output path
+	// The video folder may be shared by an artist's other videos, so the file is recorded
+	d.QueueMgr.UpdateJobOutputPath(jobID, vidPathMp4)
+	if opts.DryRun {
+		if err := d.planVideo(jobID, vidPathMp4, manifestUrl, chosen); err != nil {
+			return err
+		}
+		d.planVideoExtras(jobID, vidPathMp4, meta, tmplData, opts)
+		return nil
+	}
+	if err := os.MkdirAll(videoDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory for video %s: %w", videoDir, err)
+	}
 
 	exists, err := FileExists(vidPathMp4) // Check if final MP4 exists
 	if err != nil {
@@ -359,6 +317,7 @@ func (d *Downloader) processVideo(jobID, videoID, uguID string, opts DownloadOpt
 	}
 	if exists {
 		logger.Info("Video already exists locally, skipping download.", "jobID", jobID, "path", vidPathMp4)
+		d.postProcessVideo(jobID, vidPathMp4, meta, tmplData, opts, false)
 		return nil
 	}
 
@@ -373,12 +332,27 @@ func (d *Downloader) processVideo(jobID, videoID, uguID string, opts DownloadOpt
 	fullVariantUrl := manBaseUrl + variantMediaPlaylistUrl + query
 
 	// Get individual segment URLs from the media playlist
-	segUrls, err := d.getSegUrls(fullVariantUrl, query) // Use getSegUrls (in hls.go)
+	var media *m3u8.MediaPlaylist
+	err = d.waitForStream(jobID, opts.LiveWaitUntil, func() error {
+		var err error
+		media, err = d.fetchMediaPlaylist(fullVariantUrl)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to get video segment URLs: %w", err)
 	}
-	// Call HLS segment download with jobID
-	err = d.downloadVideoSegments(jobID, vidPathTs, manBaseUrl, segUrls)
+	if isLivePlaylist(media) {
+		// The event is still live: keep recording until it ends
+		logger.Info("Video playlist is live, switching to live capture", "jobID", jobID, "videoID", videoID)
+		err = d.captureLive(jobID, vidPathTs, manBaseUrl, fullVariantUrl, query)
+	} else {
+		segUrls := mediaSegUrls(media, query)
+		if len(segUrls) == 0 {
+			return errors.New("failed to get video segment URLs: HLS media playlist contained no segments")
+		}
+		// Call HLS segment download with jobID
+		err = d.downloadVideoSegments(jobID, vidPathTs, manBaseUrl, segUrls)
+	}
 
 	if err != nil {
 		os.Remove(vidPathTs) // Clean up partial TS file on download error
@@ -394,7 +368,7 @@ func (d *Downloader) processVideo(jobID, videoID, uguID string, opts DownloadOpt
 			chapsAvail = false
 		} else {
 			// writeChapsFile doesn't need the Downloader receiver
-			err = writeChapsFile(meta.VideoChapters, durationSecs)
+			err = writeChapsFile(vidPathTs, meta.VideoChapters, durationSecs)
 			if err != nil {
 				logger.Warn("Failed to write chapter file, chapters will be skipped.", "jobID", jobID, "error", err)
 				chapsAvail = false
@@ -412,7 +386,7 @@ func (d *Downloader) processVideo(jobID, videoID, uguID string, opts DownloadOpt
 	)
 	logger.Info("Remuxing video to MP4...", "jobID", jobID, "sourceTS", vidPathTs, "targetMP4", vidPathMp4)
 	// Call tsToMp4 method on d
-	err = d.tsToMp4(vidPathTs, vidPathMp4, chapsAvail)
+	err = d.tsToMp4(vidPathTs, vidPathMp4, chapsAvail, videoTags(meta, tmplData))
 	if err != nil {
 		// Error handling is already inside the edit; tsToMp4 cleans up on error
 		return fmt.Errorf("failed to remux video to MP4: %w", err)
@@ -420,5 +394,35 @@ func (d *Downloader) processVideo(jobID, videoID, uguID string, opts DownloadOpt
 
 	// tsToMp4 handles cleanup on success
 	logger.Info("Video processed successfully", "jobID", jobID, "finalPath", vidPathMp4)
+	d.postProcessVideo(jobID, vidPathMp4, meta, tmplData, opts, true)
 	return nil // Return nil on success
 }
+
+// postProcessVideo runs the optional steps on a downloaded video. Encoding only runs
+// on a fresh download, since a replacing profile may already have rewritten an existing file.
+// Failures are logged and don't fail the job, the video itself is already in place.
+func (d *Downloader) postProcessVideo(jobID, videoPath string, meta *AlbArtResp, data PathTemplateData, opts DownloadOptions, fresh bool) {
+	if d.config().PackageVideos {
+		durationSecs, err := d.getDuration(videoPath)
+		if err != nil {
+			logger.Warn("Could not read video duration for the .nfo", "error", err, "jobID", jobID)
+		}
+		d.writeVideoPackage(jobID, strings.TrimSuffix(videoPath, filepath.Ext(videoPath)), meta, data, durationSecs)
+	}
+	if opts.ExtractAudio || d.config().ExtractVideoAudio {
+		if _, err := d.extractVideoAudio(jobID, videoPath, d.outPath(opts), data); err != nil {
+			logger.Warn("Extracting audio from video failed", "error", err, "jobID", jobID)
+		}
+	}
+	splitAudio := opts.SplitChapters || d.config().SplitVideoChapters
+	splitClips := opts.SplitClips || d.config().SplitVideoClips
+	if splitAudio || splitClips {
+		if err := d.splitVideoChapters(jobID, videoPath, d.outPath(opts), meta, data, splitAudio, splitClips); err != nil {
+			logger.Warn("Splitting video at chapters failed", "error", err, "jobID", jobID)
+		}
+	}
+	// Encode last, so the steps above work from the original stream
+	if fresh && len(d.config().VideoEncodeProfiles) > 0 {
+		d.encodeVideo(jobID, videoPath)
+	}
+}
@@ -0,0 +1,103 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	appConfig "nugs-dl/internal/config"
+	"nugs-dl/internal/logger"
+	"nugs-dl/pkg/api"
+)
+
+// progressPhaseEncode marks progress updates sent while re-encoding a video.
+const progressPhaseEncode = "encode"
+
+// videoEncodeArgs builds the ffmpeg arguments for encoding srcPath with a profile.
+func videoEncodeArgs(srcPath, outPath string, profile appConfig.VideoEncodeProfile) []string {
+	args := []string{"-hide_banner", "-i", srcPath, "-map", "0:v:0", "-map", "0:a?", "-c:v", profile.Codec}
+	if profile.CRF > 0 {
+		args = append(args, "-crf", strconv.Itoa(profile.CRF))
+	}
+	if profile.Preset != "" {
+		args = append(args, "-preset", profile.Preset)
+	}
+	if profile.MaxHeight > 0 {
+		// Only ever downscale; -2 keeps the width even as encoders require
+		args = append(args, "-vf", fmt.Sprintf("scale=-2:'min(%d,ih)'", profile.MaxHeight))
+	}
+	if profile.Codec == "libx265" {
+		args = append(args, "-tag:v", "hvc1") // Lets Apple players recognise HEVC in MP4
+	}
+	if profile.AudioBitrate != "" {
+		args = append(args, "-c:a", "aac", "-b:a", profile.AudioBitrate)
+	} else {
+		args = append(args, "-c:a", "copy")
+	}
+	return append(args, "-movflags", "+faststart", "-y", outPath)
+}
+
+// encodeVideo runs the configured encode profiles over a downloaded video. Profiles that
+// keep the original run first; a replacing profile then overwrites the original.
+// Failures are logged and don't fail the job.
+func (d *Downloader) encodeVideo(jobID, videoPath string) {
+	var ordered []appConfig.VideoEncodeProfile
+	for _, p := range d.config().VideoEncodeProfiles {
+		if !p.Replace {
+			ordered = append(ordered, p)
+		}
+	}
+	for _, p := range d.config().VideoEncodeProfiles {
+		if p.Replace {
+			ordered = append(ordered, p)
+		}
+	}
+
+	durationSecs, err := d.getDuration(videoPath)
+	if err != nil {
+		logger.Warn("Could not read video duration, encode progress will not be reported", "error", err, "jobID", jobID)
+	}
+
+	ext := filepath.Ext(videoPath)
+	for _, profile := range ordered {
+		outPath := strings.TrimSuffix(videoPath, ext) + " [" + SanitizeFilename(profile.Name) + "]" + ext
+		if !profile.Replace {
+			if exists, _ := FileExists(outPath); exists {
+				logger.Info("Encoded video already exists, skipping", "path", outPath, "profile", profile.Name, "jobID", jobID)
+				continue
+			}
+		}
+		tmpPath := strings.TrimSuffix(outPath, ext) + ".encoding" + ext
+
+		logger.Info("Encoding video", "profile", profile.Name, "source", videoPath, "jobID", jobID)
+		message := fmt.Sprintf("Encoding video (%s)", profile.Name)
+		d.sendProgress(api.ProgressUpdate{JobID: jobID, Message: message, CurrentFile: filepath.Base(outPath), Phase: progressPhaseEncode})
+		err := d.runFfmpegWithProgress(videoEncodeArgs(videoPath, tmpPath, profile), durationSecs, func(pct float64) {
+			d.sendProgress(api.ProgressUpdate{
+				JobID:       jobID,
+				Message:     message,
+				CurrentFile: filepath.Base(outPath),
+				Percentage:  pct,
+				Phase:       progressPhaseEncode,
+			})
+		})
+		if err != nil {
+			os.Remove(tmpPath)
+			logger.Error("Video encode failed", "profile", profile.Name, "error", err, "jobID", jobID)
+			continue
+		}
+
+		target := outPath
+		if profile.Replace {
+			target = videoPath
+		}
+		if err := os.Rename(tmpPath, target); err != nil {
+			os.Remove(tmpPath)
+			logger.Error("Failed to move encoded video into place", "target", target, "error", err, "jobID", jobID)
+			continue
+		}
+		logger.Info("Encoded video", "profile", profile.Name, "path", target, "jobID", jobID)
+	}
+}
@@ -0,0 +1,183 @@
+package downloader
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"nugs-dl/internal/logger"
+)
+
+// Suffixes of the media server files written beside a video (Kodi/Jellyfin/Plex naming).
+const (
+	posterSuffix = "-poster.jpg"
+	fanartSuffix = "-fanart.jpg"
+	nfoExtension = ".nfo"
+)
+
+// videoNfo is the musicvideo .nfo schema understood by Kodi, Jellyfin and Plex.
+type videoNfo struct {
+	XMLName   xml.Name `xml:"musicvideo"`
+	Title     string   `xml:"title"`
+	Artist    string   `xml:"artist"`
+	Album     string   `xml:"album,omitempty"`
+	Premiered string   `xml:"premiered,omitempty"`
+	Year      string   `xml:"year,omitempty"`
+	Studio    string   `xml:"studio,omitempty"` // Venue
+	Genre     string   `xml:"genre"`
+	Plot      string   `xml:"plot,omitempty"`
+	Runtime   int      `xml:"runtime,omitempty"` // Minutes
+	Thumb     string   `xml:"thumb,omitempty"`
+	UniqueID  struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"uniqueid"`
+	Chapters []videoNfoChapter `xml:"chapters>chapter,omitempty"`
+}
+
+// videoNfoChapter is a chapter entry in the .nfo.
+type videoNfoChapter struct {
+	Start int    `xml:"start,attr"` // Seconds
+	Title string `xml:",chardata"`
+}
+
+// venueLine joins venue, city and state, skipping empty parts.
+func venueLine(meta *AlbArtResp) string {
+	var parts []string
+	for _, p := range []string{meta.VenueName, meta.VenueCity, meta.VenueState} {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// videoDescription summarises a show for the description atom and the .nfo plot.
+func videoDescription(meta *AlbArtResp, data PathTemplateData, marks []chapterMark) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s - %s", data.ArtistName, data.ContainerInfo)
+	if venue := venueLine(meta); venue != "" {
+		fmt.Fprintf(&b, "\n%s", venue)
+	}
+	if data.Date != "" {
+		fmt.Fprintf(&b, "\n%s", data.Date)
+	}
+	if len(marks) > 0 {
+		b.WriteString("\n")
+		for _, m := range marks {
+			fmt.Fprintf(&b, "\n%s %s", formatRunningTime(int(m.Start)), m.Title)
+		}
+	}
+	return b.String()
+}
+
+// videoTags builds the MP4 metadata atoms written during the remux.
+func videoTags(meta *AlbArtResp, data PathTemplateData) map[string]string {
+	tags := map[string]string{
+		"title":        data.ContainerInfo,
+		"artist":       data.ArtistName,
+		"album_artist": data.ArtistName,
+		"description":  videoDescription(meta, data, nil),
+	}
+	if data.Date != "" {
+		tags["date"] = data.Date
+	} else if data.Year != "" {
+		tags["date"] = data.Year
+	}
+	return tags
+}
+
+// fetchImage downloads an image URL to path, unless path already exists.
+func (d *Downloader) fetchImage(imageUrl, path string) error {
+	if !strings.HasPrefix(imageUrl, "http://") && !strings.HasPrefix(imageUrl, "https://") {
+		return fmt.Errorf("unsupported image URL %q", imageUrl)
+	}
+	if exists, _ := FileExists(path); exists {
+		return nil
+	}
+	resp, err := d.HTTPClient.Get(imageUrl)
+	if err != nil {
+		return fmt.Errorf("failed to GET image %s: %w", imageUrl, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad status for image %s: %s", imageUrl, resp.Status)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// fanartUrl picks the widest picture of a container, for use as fanart.
+func fanartUrl(meta *AlbArtResp) string {
+	best := ImageInfo{}
+	for _, p := range meta.Pics {
+		if p.URL != "" && p.Width >= best.Width {
+			best = p
+		}
+	}
+	if best.URL != "" {
+		return best.URL
+	}
+	return meta.Img.URL
+}
+
+// writeVideoPackage writes poster, fanart and .nfo files beside a video, named after it
+// (pathNoExt is the video path without extension). Failures are logged and don't fail the job.
+func (d *Downloader) writeVideoPackage(jobID, pathNoExt string, meta *AlbArtResp, data PathTemplateData, durationSecs int) {
+	poster := meta.VodPlayerImage
+	if poster == "" {
+		poster = extractArtworkUrl(meta)
+	}
+	if poster != "" {
+		if err := d.fetchImage(poster, pathNoExt+posterSuffix); err != nil {
+			logger.Warn("Failed to save video poster", "error", err, "jobID", jobID)
+		}
+	}
+	if fanart := fanartUrl(meta); fanart != "" {
+		if err := d.fetchImage(fanart, pathNoExt+fanartSuffix); err != nil {
+			logger.Warn("Failed to save video fanart", "error", err, "jobID", jobID)
+		}
+	}
+
+	var marks []chapterMark
+	if len(meta.VideoChapters) > 0 {
+		marks = videoChapterMarks(meta.VideoChapters, durationSecs)
+	}
+	nfo := videoNfo{
+		Title:     data.ContainerInfo,
+		Artist:    data.ArtistName,
+		Album:     data.ContainerInfo,
+		Premiered: data.Date,
+		Year:      data.Year,
+		Studio:    venueLine(meta),
+		Genre:     "Concert",
+		Plot:      videoDescription(meta, data, marks),
+		Runtime:   durationSecs / 60,
+		Thumb:     poster,
+	}
+	nfo.UniqueID.Type = "nugs"
+	nfo.UniqueID.Value = fmt.Sprint(meta.ContainerID)
+	for _, m := range marks {
+		nfo.Chapters = append(nfo.Chapters, videoNfoChapter{Start: int(m.Start), Title: m.Title})
+	}
+
+	out, err := xml.MarshalIndent(nfo, "", "  ")
+	if err == nil {
+		out = append([]byte(xml.Header), out...)
+		err = os.WriteFile(pathNoExt+nfoExtension, out, 0644)
+	}
+	if err != nil {
+		logger.Warn("Failed to write video .nfo", "error", err, "jobID", jobID)
+	}
+}
@@ -0,0 +1,236 @@
+package downloader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"nugs-dl/internal/logger"
+	"nugs-dl/pkg/api"
+)
+
+// Progress phases for video post-processing.
+const (
+	progressPhaseSplit   = "split"   // Cutting a video at its chapters
+	progressPhaseExtract = "extract" // Extracting the full audio of a video
+)
+
+// videoClipsSuffix is appended to the video path (without extension) for the clips folder.
+const videoClipsSuffix = " - Clips"
+
+// cutSegment copies the part of srcPath between start and end seconds into outPath.
+// When audioOnly is set only the audio stream is kept.
+func (d *Downloader) cutSegment(srcPath, outPath string, start, end int, audioOnly bool, tags map[string]string) error {
+	ext := filepath.Ext(outPath)
+	tmpPath := strings.TrimSuffix(outPath, ext) + ".cutting" + ext
+
+	args := []string{"-hide_banner", "-ss", strconv.Itoa(start)}
+	if end > start {
+		args = append(args, "-to", strconv.Itoa(end))
+	}
+	args = append(args, "-i", srcPath)
+	if audioOnly {
+		args = append(args, "-map", "0:a")
+	} else {
+		args = append(args, "-map", "0")
+	}
+	// Drop the whole-show chapters and tags; each cut gets its own
+	args = append(args, "-map_chapters", "-1", "-map_metadata", "-1", "-c", "copy")
+	args = append(args, metadataArgs(tags)...)
+	args = append(args, "-y", tmpPath)
+
+	var errBuffer bytes.Buffer
+	cmd := exec.Command(d.getFfmpegCmd(), args...)
+	cmd.Stderr = &errBuffer
+	logger.Debug("Executing FFmpeg cut command", "arguments", args)
+	if err := cmd.Run(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("ffmpeg cut failed: %w\nOutput:\n%s", err, errBuffer.String())
+	}
+	if err := os.Rename(tmpPath, outPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to move cut file to %s: %w", outPath, err)
+	}
+	return nil
+}
+
+// showTags builds the tags for a whole-show file from container template data.
+func showTags(data PathTemplateData) map[string]string {
+	tags := trackTags(data)
+	delete(tags, "track")
+	delete(tags, "disc")
+	tags["title"] = data.ContainerInfo
+	return tags
+}
+
+// splitAudioQuality describes songs cut from a video. Audio in nugs.net videos is AAC,
+// which is stream-copied.
+var splitAudioQuality = &Quality{Format: 5, Specs: "AAC (from video)", Extension: ".m4a"}
+
+// splitTrackData returns the template data of song num of total cut from a video.
+func splitTrackData(data PathTemplateData, title string, num, total int) PathTemplateData {
+	trackData := data.withTrack(&Track{SongTitle: title}, num, total).withQuality(splitAudioQuality)
+	trackData.DiscNum, trackData.DiscTotal = 1, 1
+	return trackData
+}
+
+// extractedAudioPath returns the file extractVideoAudio writes for a video.
+func (d *Downloader) extractedAudioPath(audioRoot string, data PathTemplateData) (string, error) {
+	albumDir, err := d.albumFolderPath(audioRoot, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to build album folder path: %w", err)
+	}
+	return filepath.Join(albumDir, filepath.Base(albumDir)+".m4a"), nil
+}
+
+// extractVideoAudio writes the audio of a downloaded video as one tagged file into the
+// album folder of the audio library beneath audioRoot. The audio is stream-copied into
+// M4A; if that fails (e.g. the codec doesn't fit the container) it is transcoded to AAC instead.
+func (d *Downloader) extractVideoAudio(jobID, videoPath, audioRoot string, data PathTemplateData) (string, error) {
+	outPath, err := d.extractedAudioPath(audioRoot, data)
+	if err != nil {
+		return "", err
+	}
+	if err := MakeDirs(filepath.Dir(outPath)); err != nil {
+		return "", err
+	}
+	if exists, _ := FileExists(outPath); exists {
+		logger.Info("Extracted audio already exists, skipping", "path", outPath, "jobID", jobID)
+		return outPath, nil
+	}
+	tmpPath := strings.TrimSuffix(outPath, ".m4a") + ".extracting.m4a"
+
+	d.sendProgress(api.ProgressUpdate{
+		JobID:       jobID,
+		Message:     "Extracting audio from video",
+		CurrentFile: filepath.Base(outPath),
+		Percentage:  100.0,
+		Phase:       progressPhaseExtract,
+	})
+
+	tags := metadataArgs(showTags(data))
+	run := func(codecArgs ...string) error {
+		args := []string{"-hide_banner", "-i", videoPath, "-map", "0:a", "-map_metadata", "-1"}
+		args = append(args, codecArgs...)
+		args = append(args, tags...)
+		args = append(args, "-y", tmpPath)
+		var errBuffer bytes.Buffer
+		cmd := exec.Command(d.getFfmpegCmd(), args...)
+		cmd.Stderr = &errBuffer
+		logger.Debug("Executing FFmpeg audio extract command", "arguments", args)
+		if err := cmd.Run(); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("ffmpeg audio extract failed: %w\nOutput:\n%s", err, errBuffer.String())
+		}
+		return nil
+	}
+	if err := run("-c:a", "copy"); err != nil {
+		logger.Warn("Stream copy of video audio failed, transcoding to AAC", "error", err, "jobID", jobID)
+		if err := run("-c:a", "aac", "-b:a", "256k"); err != nil {
+			return "", err
+		}
+	}
+	if err := os.Rename(tmpPath, outPath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to move extracted audio to %s: %w", outPath, err)
+	}
+	logger.Info("Extracted audio from video", "path", outPath, "jobID", jobID)
+	return outPath, nil
+}
+
+// splitVideoChapters cuts a downloaded video at its chapter boundaries. With audio set,
+// each song's audio is written as a numbered, tagged track into the album folder of the
+// audio library beneath audioRoot; with clips set, per-song MP4 clips are written into
+// a folder beside the video.
+func (d *Downloader) splitVideoChapters(jobID, videoPath, audioRoot string, meta *AlbArtResp, data PathTemplateData, audio, clips bool) error {
+	if len(meta.VideoChapters) == 0 {
+		return errors.New("video has no chapters")
+	}
+	durationSecs, err := d.getDuration(videoPath)
+	if err != nil {
+		return fmt.Errorf("failed to get video duration: %w", err)
+	}
+	marks := videoChapterMarks(meta.VideoChapters, durationSecs)
+	if len(marks) == 0 {
+		return errors.New("video chapters could not be parsed")
+	}
+
+	var audioDir, clipsDir string
+	if audio {
+		audioDir, err = d.albumFolderPath(audioRoot, data)
+		if err != nil {
+			return fmt.Errorf("failed to build album folder path: %w", err)
+		}
+	}
+	if clips {
+		clipsDir = strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + videoClipsSuffix
+	}
+
+	audioQual := splitAudioQuality
+	total := len(marks)
+	var firstErr error
+	for i, m := range marks {
+		// Cut at the next chapter's start so nothing is lost between songs
+		end := durationSecs
+		if i+1 < total {
+			end = int(marks[i+1].Start)
+		}
+		trackData := splitTrackData(data, m.Title, i+1, total)
+		tags := trackTags(trackData)
+
+		d.sendProgress(api.ProgressUpdate{
+			JobID:        jobID,
+			Message:      fmt.Sprintf("Splitting song %d/%d", i+1, total),
+			CurrentFile:  m.Title,
+			Percentage:   float64(i) / float64(total) * 100.0,
+			CurrentTrack: i + 1,
+			TotalTracks:  total,
+			Phase:        progressPhaseSplit,
+		})
+
+		if audio {
+			rel, err := d.trackFileName(trackData, audioQual.Extension)
+			if err == nil {
+				outPath := filepath.Join(audioDir, rel)
+				if err = MakeDirs(filepath.Dir(outPath)); err == nil {
+					err = d.cutSegment(videoPath, outPath, int(m.Start), end, true, tags)
+				}
+				if err == nil {
+					d.QueueMgr.AddJobTrackResult(jobID, api.TrackResult{
+						TrackNum: i + 1, Title: m.Title, Format: audioQual.Format, FormatName: formatNames[audioQual.Format],
+						Specs: audioQual.Specs, Wanted: audioQual.Format, Path: outPath,
+					})
+				}
+			}
+			if err != nil {
+				logger.Error("Failed to split song audio from video", "song", m.Title, "error", err, "jobID", jobID)
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+
+		if clips {
+			rel, err := d.trackFileName(trackData, ".mp4")
+			if err == nil {
+				outPath := filepath.Join(clipsDir, rel)
+				if err = MakeDirs(filepath.Dir(outPath)); err == nil {
+					err = d.cutSegment(videoPath, outPath, int(m.Start), end, false, tags)
+				}
+			}
+			if err != nil {
+				logger.Error("Failed to cut song clip from video", "song", m.Title, "error", err, "jobID", jobID)
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+	}
+	logger.Info("Split video at chapters", "songs", total, "audio", audio, "clips", clips, "jobID", jobID)
+	return firstErr
+}
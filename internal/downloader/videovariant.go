@@ -0,0 +1,165 @@
+package downloader
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"nugs-dl/internal/logger"
+	"nugs-dl/pkg/api"
+
+	"github.com/grafov/m3u8"
+)
+
+// Video codec preferences.
+const (
+	VideoCodecAny  = ""
+	VideoCodecH264 = "h264"
+	VideoCodecHEVC = "hevc"
+)
+
+// variantInfo holds the parsed properties of a video variant.
+type variantInfo struct {
+	Variant   *m3u8.Variant
+	Width     int
+	Height    int
+	Codec     string // h264, hevc, or the raw codec string if unknown
+	FrameRate float64
+	Bandwidth uint32
+}
+
+// videoPreference describes which variant to pick. Zero caps mean no limit.
+type videoPreference struct {
+	MaxHeight    int
+	MaxFrameRate float64
+	MaxBandwidth uint32 // bits/s
+	Codec        string // Preferred codec; other codecs are still used if needed
+}
+
+// IsValidVideoCodec reports whether codec is a known codec preference (empty means any).
+func IsValidVideoCodec(codec string) bool {
+	switch codec {
+	case VideoCodecAny, VideoCodecH264, VideoCodecHEVC:
+		return true
+	}
+	return false
+}
+
+// videoCodecName maps an HLS CODECS attribute to a codec name.
+func videoCodecName(codecs string) string {
+	for _, c := range strings.Split(codecs, ",") {
+		c = strings.TrimSpace(strings.ToLower(c))
+		switch {
+		case strings.HasPrefix(c, "avc1"), strings.HasPrefix(c, "avc3"):
+			return VideoCodecH264
+		case strings.HasPrefix(c, "hvc1"), strings.HasPrefix(c, "hev1"):
+			return VideoCodecHEVC
+		}
+	}
+	return codecs
+}
+
+// parseVariant extracts width, height, codec, frame rate and bandwidth from a variant.
+// Missing values are left zero.
+func parseVariant(v *m3u8.Variant) variantInfo {
+	info := variantInfo{Variant: v, Codec: videoCodecName(v.Codecs), FrameRate: v.FrameRate, Bandwidth: v.AverageBandwidth}
+	if info.Bandwidth == 0 {
+		info.Bandwidth = v.Bandwidth
+	}
+	if w, h, ok := strings.Cut(v.Resolution, "x"); ok {
+		info.Width, _ = strconv.Atoi(w)
+		info.Height, _ = strconv.Atoi(h)
+	}
+	return info
+}
+
+// within reports whether a variant respects the caps of the preference.
+func (p videoPreference) within(v variantInfo) bool {
+	if p.MaxHeight > 0 && v.Height > p.MaxHeight {
+		return false
+	}
+	if p.MaxFrameRate > 0 && v.FrameRate > p.MaxFrameRate {
+		return false
+	}
+	if p.MaxBandwidth > 0 && v.Bandwidth > p.MaxBandwidth {
+		return false
+	}
+	return true
+}
+
+// better reports whether a should be preferred over b: higher resolution first,
+// then the preferred codec, then higher frame rate, then higher bandwidth.
+func (p videoPreference) better(a, b variantInfo) bool {
+	if a.Height != b.Height {
+		return a.Height > b.Height
+	}
+	if p.Codec != VideoCodecAny && (a.Codec == p.Codec) != (b.Codec == p.Codec) {
+		return a.Codec == p.Codec
+	}
+	if a.FrameRate != b.FrameRate {
+		return a.FrameRate > b.FrameRate
+	}
+	return a.Bandwidth > b.Bandwidth
+}
+
+// selectVariant picks the best variant that respects the caps. If none does,
+// the smallest variant is used so the result stays as close to the caps as possible.
+func selectVariant(variants []*m3u8.Variant, pref videoPreference) (variantInfo, error) {
+	var all, allowed []variantInfo
+	for _, v := range variants {
+		if v == nil || v.Iframe {
+			continue
+		}
+		info := parseVariant(v)
+		all = append(all, info)
+		if pref.within(info) {
+			allowed = append(allowed, info)
+		}
+	}
+	if len(all) == 0 {
+		return variantInfo{}, fmt.Errorf("no playable video variants found")
+	}
+	if len(allowed) == 0 {
+		sort.Slice(all, func(i, j int) bool { return pref.better(all[j], all[i]) })
+		logger.Info("No video variant within the configured caps, using the smallest available", "maxHeight", pref.MaxHeight, "maxFrameRate", pref.MaxFrameRate, "maxBandwidth", pref.MaxBandwidth, "resolution", all[0].Variant.Resolution)
+		return all[0], nil
+	}
+	sort.Slice(allowed, func(i, j int) bool { return pref.better(allowed[i], allowed[j]) })
+	return allowed[0], nil
+}
+
+// videoPreferenceFor builds the variant preference from the config. The video
+// format sets the resolution cap; 4K/Best (5) leaves the resolution unlimited.
+func (d *Downloader) videoPreferenceFor(videoFormat int) videoPreference {
+	pref := videoPreference{
+		MaxFrameRate: d.config().VideoMaxFrameRate,
+		MaxBandwidth: uint32(d.config().VideoMaxBitrateKbps) * 1000,
+		Codec:        d.config().VideoCodec,
+	}
+	if videoFormat != 5 {
+		pref.MaxHeight, _ = strconv.Atoi(resolveRes[videoFormat])
+	}
+	return pref
+}
+
+// resolutionLabel returns the display resolution of a variant, e.g. "1080p" or "4K".
+func (v variantInfo) resolutionLabel() string {
+	if v.Height == 0 {
+		return "unknown"
+	}
+	return formatRes(strconv.Itoa(v.Height))
+}
+
+// toAPI converts the variant properties for recording on the job.
+func (v variantInfo) toAPI() *api.VideoVariant {
+	return &api.VideoVariant{
+		Resolution: v.resolutionLabel(),
+		Width:      v.Width,
+		Height:     v.Height,
+		Codec:      v.Codec,
+		Codecs:     v.Variant.Codecs,
+		FrameRate:  v.FrameRate,
+		Bandwidth:  v.Bandwidth,
+	}
+}
@@ -0,0 +1,52 @@
+package downloader
+
+import (
+	"testing"
+
+	"github.com/grafov/m3u8"
+)
+
+func variant(resolution, codecs string, frameRate float64, bandwidth uint32) *m3u8.Variant {
+	return &m3u8.Variant{VariantParams: m3u8.VariantParams{Resolution: resolution, Codecs: codecs, FrameRate: frameRate, Bandwidth: bandwidth}}
+}
+
+func TestSelectVariant(t *testing.T) {
+	v720 := variant("1280x720", "avc1.4d401f,mp4a.40.2", 30, 2500000)
+	v1080 := variant("1920x1080", "avc1.640028,mp4a.40.2", 30, 5000000)
+	v1080hevc := variant("1920x1080", "hvc1.1.6.L120.90,mp4a.40.2", 30, 4000000)
+	v1080p60 := variant("1920x1080", "avc1.640028,mp4a.40.2", 60, 7000000)
+	v2160 := variant("3840x2160", "hvc1.2.4.L153.B0,mp4a.40.2", 30, 16000000)
+	iframe := &m3u8.Variant{VariantParams: m3u8.VariantParams{Resolution: "7680x4320", Iframe: true}}
+
+	tests := []struct {
+		name     string
+		variants []*m3u8.Variant
+		pref     videoPreference
+		want     *m3u8.Variant
+	}{
+		{"highest without caps", []*m3u8.Variant{v720, v2160, v1080}, videoPreference{}, v2160},
+		{"height cap", []*m3u8.Variant{v720, v2160, v1080}, videoPreference{MaxHeight: 1080}, v1080},
+		{"preferred codec at same height", []*m3u8.Variant{v1080, v1080hevc}, videoPreference{Codec: VideoCodecHEVC}, v1080hevc},
+		{"resolution beats codec", []*m3u8.Variant{v720, v1080hevc}, videoPreference{Codec: VideoCodecH264}, v1080hevc},
+		{"frame rate cap", []*m3u8.Variant{v1080, v1080p60}, videoPreference{MaxFrameRate: 30}, v1080},
+		{"higher frame rate without cap", []*m3u8.Variant{v1080, v1080p60}, videoPreference{}, v1080p60},
+		{"bandwidth cap", []*m3u8.Variant{v720, v1080}, videoPreference{MaxBandwidth: 3000000}, v720},
+		{"smallest when nothing fits", []*m3u8.Variant{v1080, v720}, videoPreference{MaxHeight: 480}, v720},
+		{"iframe streams ignored", []*m3u8.Variant{iframe, v720, nil}, videoPreference{}, v720},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectVariant(tt.variants, tt.pref)
+			if err != nil {
+				t.Fatalf("selectVariant() error = %v", err)
+			}
+			if got.Variant != tt.want {
+				t.Errorf("selectVariant() = %s %s, want %s %s", got.Variant.Resolution, got.Variant.Codecs, tt.want.Resolution, tt.want.Codecs)
+			}
+		})
+	}
+
+	if _, err := selectVariant([]*m3u8.Variant{iframe}, videoPreference{}); err == nil {
+		t.Error("selectVariant() with only iframe streams succeeded, want error")
+	}
+}
@@ -0,0 +1,306 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"nugs-dl/internal/broadcast"
+	appConfig "nugs-dl/internal/config"
+	"nugs-dl/internal/downloader"
+	"nugs-dl/internal/logger"
+	"nugs-dl/internal/queue"
+	"nugs-dl/pkg/api"
+)
+
+// Monitor settings.
+const (
+	stateFileName        = "monitor_state.json"
+	defaultIntervalHours = 6
+	pollInterval         = time.Minute // How often due artists are looked for
+	maxFoundPerArtist    = 50          // Finds kept in the state per artist
+)
+
+// ErrCheckRunning is returned when a check is requested while one is running.
+var ErrCheckRunning = errors.New("a monitor check is already running")
+
+// artistState is what the monitor remembers about an artist between runs.
+type artistState struct {
+	Name      string            `json:"name"`
+	LastCheck *time.Time        `json:"lastCheck,omitempty"`
+	LastError string            `json:"lastError,omitempty"`
+	Known     []int             `json:"known"` // Container IDs seen so far
+	Found     []api.MonitorFind `json:"found,omitempty"`
+}
+
+// state is persisted to the monitor state file.
+type state struct {
+	Artists map[string]*artistState `json:"artists"` // Keyed by artist ID
+}
+
+// ReleaseNotifier is told about new releases the monitor finds.
+type ReleaseNotifier interface {
+	ReleaseFound(artistID int, artistName string, find api.MonitorFind)
+}
+
+// Monitor periodically checks the configured artists for new releases and queues them.
+type Monitor struct {
+	Notifier ReleaseNotifier // Optional
+
+	config    func() *appConfig.AppConfig // Returns the current configuration
+	dl        *downloader.Downloader
+	qm        *queue.QueueManager
+	hub       *broadcast.Hub
+	statePath string
+
+	mu       sync.Mutex
+	state    state
+	checking bool
+}
+
+// NewMonitor creates a monitor and loads its state file, if present.
+func NewMonitor(config func() *appConfig.AppConfig, dl *downloader.Downloader, qm *queue.QueueManager, hub *broadcast.Hub) *Monitor {
+	m := &Monitor{
+		config:    config,
+		dl:        dl,
+		qm:        qm,
+		hub:       hub,
+		statePath: appConfig.StatePath(stateFileName),
+		state:     state{Artists: make(map[string]*artistState)},
+	}
+	data, err := os.ReadFile(m.statePath)
+	if err == nil {
+		if err := json.Unmarshal(data, &m.state); err != nil {
+			logger.Error("[Monitor] Failed to parse state file, starting fresh", "path", m.statePath, "error", err)
+		}
+		if m.state.Artists == nil {
+			m.state.Artists = make(map[string]*artistState)
+		}
+	} else if !os.IsNotExist(err) {
+		logger.Error("[Monitor] Failed to read state file", "path", m.statePath, "error", err)
+	}
+	return m
+}
+
+// Start launches the background loop that checks artists when their interval has
+// elapsed. Nothing is checked while monitoring is disabled in the config.
+func (m *Monitor) Start() {
+	logger.Info("[Monitor] Starting artist monitor...")
+	go func() {
+		for {
+			cfg := m.config()
+			if cfg.Monitor {
+				for _, artist := range cfg.Artists {
+					if artist.Enabled && m.due(artist, cfg) {
+						if err := m.CheckArtist(artist.ID); err != nil && !errors.Is(err, ErrCheckRunning) {
+							logger.Warn("[Monitor] Artist check failed", "artistID", artist.ID, "name", artist.Name, "error", err)
+						}
+					}
+				}
+			}
+			time.Sleep(pollInterval)
+		}
+	}()
+}
+
+// intervalFor returns the check interval of an artist, falling back to the global one.
+func intervalFor(artist appConfig.ArtistConfig, cfg *appConfig.AppConfig) int {
+	if artist.MonitorIntervalHours > 0 {
+		return artist.MonitorIntervalHours
+	}
+	if cfg.MonitorIntervalHours > 0 {
+		return cfg.MonitorIntervalHours
+	}
+	return defaultIntervalHours
+}
+
+// due reports whether an artist's interval has elapsed since its last check.
+func (m *Monitor) due(artist appConfig.ArtistConfig, cfg *appConfig.AppConfig) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.state.Artists[strconv.Itoa(artist.ID)]
+	if st == nil || st.LastCheck == nil {
+		return true
+	}
+	return time.Since(*st.LastCheck) >= time.Duration(intervalFor(artist, cfg))*time.Hour
+}
+
+// CheckNow checks every enabled artist right away, regardless of their intervals.
+func (m *Monitor) CheckNow() error {
+	cfg := m.config()
+	var firstErr error
+	for _, artist := range cfg.Artists {
+		if !artist.Enabled {
+			continue
+		}
+		if err := m.CheckArtist(artist.ID); err != nil {
+			if errors.Is(err, ErrCheckRunning) {
+				return err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// CheckArtist lists an artist's releases and queues those it hasn't seen before,
+// unless they're already in the library. The first check of an artist only records
+// the existing releases, so adding an artist doesn't queue its whole back catalogue.
+func (m *Monitor) CheckArtist(artistID int) error {
+	cfg := m.config()
+	var artist *appConfig.ArtistConfig
+	for i := range cfg.Artists {
+		if cfg.Artists[i].ID == artistID {
+			artist = &cfg.Artists[i]
+			break
+		}
+	}
+	if artist == nil {
+		return fmt.Errorf("artist %d is not configured", artistID)
+	}
+
+	m.mu.Lock()
+	if m.checking {
+		m.mu.Unlock()
+		return ErrCheckRunning
+	}
+	m.checking = true
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.checking = false
+		m.mu.Unlock()
+	}()
+
+	key := strconv.Itoa(artistID)
+	logger.Info("[Monitor] Checking artist for new releases", "artistID", artistID, "name", artist.Name)
+	name, releases, err := m.dl.ArtistReleases(key, artist.OutPath)
+	now := time.Now().UTC()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.state.Artists[key]
+	if st == nil {
+		st = &artistState{}
+		m.state.Artists[key] = st
+	}
+	st.LastCheck = &now
+	if err != nil {
+		st.LastError = err.Error()
+		m.saveLocked()
+		return err
+	}
+	st.LastError = ""
+	if name != "" {
+		st.Name = name
+	}
+
+	baseline := st.Known == nil
+	known := make(map[int]bool, len(st.Known))
+	for _, id := range st.Known {
+		known[id] = true
+	}
+	var finds []api.MonitorFind
+	for _, r := range releases {
+		if known[r.ContainerID] {
+			continue
+		}
+		known[r.ContainerID] = true
+		st.Known = append(st.Known, r.ContainerID)
+		if baseline {
+			continue
+		}
+
+		find := api.MonitorFind{ContainerID: r.ContainerID, Title: r.Title, Date: r.Date, FoundAt: now}
+		if r.InLibrary {
+			find.Skipped = "already in the library"
+		} else {
+			opts := api.DownloadOptions{Format: artist.Format, VideoFormat: artist.VideoFormat, OutPath: artist.OutPath}
+			job, err := m.qm.AddJob(downloader.ReleaseURL(r.ContainerID), opts)
+			if err != nil {
+				find.Error = err.Error()
+			} else {
+				find.JobID = job.ID
+				m.hub.BroadcastJobAdded(job)
+			}
+		}
+		logger.Info("[Monitor] New release found", "artistID", artistID, "containerID", r.ContainerID, "title", r.Title, "jobID", find.JobID, "skipped", find.Skipped, "error", find.Error)
+		finds = append(finds, find)
+		if m.Notifier != nil {
+			go m.Notifier.ReleaseFound(artistID, st.Name, find)
+		}
+	}
+	if st.Known == nil {
+		st.Known = []int{} // Remember that the baseline was taken, even for an empty catalogue
+	}
+	if baseline {
+		logger.Info("[Monitor] Recorded existing releases for newly monitored artist", "artistID", artistID, "releases", len(st.Known))
+	}
+
+	// Newest finds first
+	for i := len(finds) - 1; i >= 0; i-- {
+		st.Found = append([]api.MonitorFind{finds[i]}, st.Found...)
+	}
+	if len(st.Found) > maxFoundPerArtist {
+		st.Found = st.Found[:maxFoundPerArtist]
+	}
+	m.saveLocked()
+	return nil
+}
+
+// saveLocked writes the state file. The caller must hold m.mu.
+func (m *Monitor) saveLocked() {
+	data, err := json.MarshalIndent(m.state, "", "  ")
+	if err == nil {
+		err = os.WriteFile(m.statePath, data, 0644)
+	}
+	if err != nil {
+		logger.Error("[Monitor] Failed to save state file", "path", m.statePath, "error", err)
+	}
+}
+
+// Status reports the monitor configuration and the state of every configured artist.
+func (m *Monitor) Status() api.MonitorStatus {
+	cfg := m.config()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := api.MonitorStatus{
+		Enabled:       cfg.Monitor,
+		IntervalHours: cfg.MonitorIntervalHours,
+		Checking:      m.checking,
+		Artists:       make([]api.MonitorArtistStatus, 0, len(cfg.Artists)),
+	}
+	if status.IntervalHours <= 0 {
+		status.IntervalHours = defaultIntervalHours
+	}
+	for _, artist := range cfg.Artists {
+		as := api.MonitorArtistStatus{
+			ID:            artist.ID,
+			Name:          artist.Name,
+			Enabled:       artist.Enabled,
+			IntervalHours: intervalFor(artist, cfg),
+		}
+		if st := m.state.Artists[strconv.Itoa(artist.ID)]; st != nil {
+			if as.Name == "" {
+				as.Name = st.Name
+			}
+			as.LastCheck = st.LastCheck
+			as.LastError = st.LastError
+			as.KnownReleases = len(st.Known)
+			as.Found = st.Found
+			if st.LastCheck != nil && cfg.Monitor && artist.Enabled {
+				next := st.LastCheck.Add(time.Duration(as.IntervalHours) * time.Hour)
+				as.NextCheck = &next
+			}
+		}
+		status.Artists = append(status.Artists, as)
+	}
+	return status
+}
@@ -0,0 +1,245 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	appConfig "nugs-dl/internal/config"
+	"nugs-dl/internal/filesystem"
+	"nugs-dl/internal/logger"
+	"nugs-dl/pkg/api"
+)
+
+// Priorities of events that need attention; others use the configured gotifyPriority.
+const (
+	priorityPartial = 6
+	priorityFailed  = 8
+	priorityLowDisk = 8
+)
+
+const requestTimeout = 10 * time.Second
+
+// Gotify sends notifications to a Gotify server. It reads the current config on every
+// send, so notifications can be turned on or off without a restart.
+type Gotify struct {
+	config func() *appConfig.AppConfig // Returns the current configuration
+	client *http.Client
+
+	mu      sync.Mutex
+	lowDisk map[string]bool // Paths already reported as low on space, until they recover
+}
+
+// NewGotify creates a Gotify notifier.
+func NewGotify(config func() *appConfig.AppConfig) *Gotify {
+	return &Gotify{
+		config:  config,
+		client:  &http.Client{Timeout: requestTimeout},
+		lowDisk: make(map[string]bool),
+	}
+}
+
+// message is the body of Gotify's POST /message.
+type message struct {
+	Title    string                 `json:"title"`
+	Message  string                 `json:"message"`
+	Priority int                    `json:"priority"`
+	Extras   map[string]interface{} `json:"extras,omitempty"`
+}
+
+// Send posts a notification. Markdown in text is rendered by Gotify clients; an
+// artwork URL is shown as the notification's image.
+func (g *Gotify) Send(title, text string, priority int, artworkURL string) error {
+	cfg := g.config()
+	if cfg.GotifyURL == "" || cfg.GotifyToken == "" {
+		return errors.New("gotifyUrl and gotifyToken must be set")
+	}
+	msg := message{
+		Title:    title,
+		Message:  text,
+		Priority: priority,
+		Extras: map[string]interface{}{
+			"client::display": map[string]string{"contentType": "text/markdown"},
+		},
+	}
+	if artworkURL != "" {
+		msg.Extras["client::notification"] = map[string]string{"bigImageUrl": artworkURL}
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	endpoint := strings.TrimRight(cfg.GotifyURL, "/") + "/message?token=" + url.QueryEscape(cfg.GotifyToken)
+	resp, err := g.client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to reach Gotify: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Gotify rejected the notification: %s", resp.Status)
+	}
+	logger.Debug("[Notify] Notification sent", "title", title, "priority", priority)
+	return nil
+}
+
+// send posts a notification if notifications are enabled, logging failures.
+func (g *Gotify) send(title, text string, priority int, artworkURL string) {
+	cfg := g.config()
+	if !cfg.Notifications || cfg.GotifyURL == "" || cfg.GotifyToken == "" {
+		return
+	}
+	if priority == 0 {
+		priority = cfg.GotifyPriority
+	}
+	if err := g.Send(title, text, priority, artworkURL); err != nil {
+		logger.Warn("[Notify] Failed to send notification", "title", title, "error", err)
+	}
+}
+
+// atLeast returns the configured priority, raised to min for important events.
+func atLeast(cfg *appConfig.AppConfig, min int) int {
+	if cfg.GotifyPriority > min {
+		return cfg.GotifyPriority
+	}
+	return min
+}
+
+// Notify sends a plain event raised by the downloader, such as a live recording starting.
+func (g *Gotify) Notify(title, text string) {
+	g.send(title, text, 0, "")
+}
+
+// artistEnabled reports whether notifications are on for an artist, honouring its override.
+func artistEnabled(cfg *appConfig.AppConfig, artistID int) bool {
+	if artist, ok := cfg.GetEffectiveArtistConfig(artistID); ok && artistID != 0 {
+		return *artist.Notifications
+	}
+	return cfg.Notifications
+}
+
+// jobName returns the artist and show title of a job, falling back to its URL. The
+// artist is added when the title doesn't already name it, as video titles don't.
+func jobName(job *api.DownloadJob) string {
+	if job.Title == "" {
+		return job.OriginalUrl
+	}
+	if job.Effective != nil && job.Effective.ArtistName != "" &&
+		!strings.Contains(strings.ToLower(job.Title), strings.ToLower(job.Effective.ArtistName)) {
+		return job.Effective.ArtistName + " - " + job.Title
+	}
+	return job.Title
+}
+
+// withArtwork appends a link to the artwork of a job, if it has one.
+func withArtwork(text, artworkURL string) string {
+	if artworkURL == "" {
+		return text
+	}
+	return text + fmt.Sprintf("\n\n[Artwork](%s)", artworkURL)
+}
+
+// JobFinished reports a completed or failed job. Completed jobs with failed tracks are
+// reported as partial; copies in extra formats don't count, as those may be missing
+// by design. Dry runs and artists that opted out are skipped.
+func (g *Gotify) JobFinished(job *api.DownloadJob) {
+	cfg := g.config()
+	if job.DryRun {
+		return
+	}
+	if job.Effective != nil && !job.Effective.Notifications {
+		logger.Debug("[Notify] Notifications are off for the job's artist", "jobID", job.ID, "artistID", job.Effective.ArtistID)
+		return
+	}
+
+	name := jobName(job)
+	switch job.Status {
+	case api.StatusComplete:
+		failed, total := 0, 0
+		for _, t := range job.Tracks {
+			if t.Extra {
+				continue
+			}
+			total++
+			if t.Error != "" {
+				failed++
+			}
+		}
+		if failed > 0 {
+			text := fmt.Sprintf("%d of %d tracks failed to download.", failed, total)
+			g.send("Partially downloaded: "+name, withArtwork(text, job.ArtworkURL), atLeast(cfg, priorityPartial), job.ArtworkURL)
+			break
+		}
+		text := "Download complete."
+		if total > 0 {
+			text = fmt.Sprintf("Downloaded %d tracks.", total)
+		}
+		if job.Degraded {
+			text += " Some tracks fell back from the preferred format."
+		}
+		if job.OutputPath != "" {
+			text += fmt.Sprintf("\n\nSaved to `%s`", job.OutputPath)
+		}
+		g.send("Downloaded: "+name, withArtwork(text, job.ArtworkURL), 0, job.ArtworkURL)
+	case api.StatusFailed:
+		g.send("Download failed: "+name, withArtwork(job.ErrorMessage, job.ArtworkURL), atLeast(cfg, priorityFailed), job.ArtworkURL)
+	}
+
+	g.CheckDiskSpace()
+}
+
+// ReleaseFound reports a new release the monitor found for an artist.
+func (g *Gotify) ReleaseFound(artistID int, artistName string, find api.MonitorFind) {
+	cfg := g.config()
+	if !artistEnabled(cfg, artistID) {
+		return
+	}
+	text := find.Title
+	if find.Date != "" {
+		text += " (" + find.Date + ")"
+	}
+	switch {
+	case find.JobID != "":
+		text += "\n\nQueued for download."
+	case find.Skipped != "":
+		text += "\n\nNot queued: " + find.Skipped + "."
+	case find.Error != "":
+		text += "\n\nFailed to queue: " + find.Error
+	}
+	g.send("New release: "+artistName, text, 0, "")
+}
+
+// CheckDiskSpace warns when a download location has less free space than
+// diskSpaceLowWarningGB. Each location is reported once until it recovers.
+func (g *Gotify) CheckDiskSpace() {
+	cfg := g.config()
+	if !cfg.CheckDiskSpace || cfg.DiskSpaceLowWarningGB <= 0 {
+		return
+	}
+	paths := []string{cfg.OutPath}
+	if cfg.LiveVideoPath != "" && cfg.LiveVideoPath != cfg.OutPath {
+		paths = append(paths, cfg.LiveVideoPath)
+	}
+	threshold := uint64(cfg.DiskSpaceLowWarningGB) * 1024 * 1024 * 1024
+	for _, path := range paths {
+		free, err := filesystem.GetAvailableDiskSpace(path)
+		if err != nil {
+			continue
+		}
+		g.mu.Lock()
+		reported := g.lowDisk[path]
+		g.lowDisk[path] = free < threshold
+		g.mu.Unlock()
+		if free < threshold && !reported {
+			text := fmt.Sprintf("Only %.1f GB free at `%s` (warning below %d GB).", float64(free)/(1024*1024*1024), path, cfg.DiskSpaceLowWarningGB)
+			logger.Warn("[Notify] Low disk space", "path", path, "freeBytes", free)
+			g.send("Low disk space", text, atLeast(cfg, priorityLowDisk), "")
+		}
+	}
+}
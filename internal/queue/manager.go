@@ -33,7 +33,7 @@ func (qm *QueueManager) AddJob(url string, opts api.DownloadOptions) (*api.Downl
 	// Check for existing active/queued job with the same URL
 	for _, existingJob := range qm.jobs {
 		if existingJob.OriginalUrl == url &&
-			(existingJob.Status == api.StatusQueued || existingJob.Status == api.StatusProcessing) {
+			(existingJob.Status == api.StatusQueued || existingJob.Status == api.StatusProcessing || existingJob.Status == api.StatusScheduled) {
 			logger.Warn("Attempted to add a duplicate job for URL already in queue/processing", "url", url, "existingJobID", existingJob.ID, "existingStatus", existingJob.Status)
 			return nil, fmt.Errorf("job for URL %s already exists in queue (ID: %s, Status: %s)", url, existingJob.ID, existingJob.Status)
 		}
@@ -89,7 +89,7 @@ func (qm *QueueManager) GetCompletedJobs() []*api.DownloadJob {
 	// Initialize with empty slice to ensure JSON serializes as [] not null
 	completedJobs := make([]*api.DownloadJob, 0)
 	for _, job := range qm.jobs {
-		if job.Status == api.StatusComplete {
+		if job.Status == api.StatusComplete && !job.DryRun { // Dry runs have no files to show
 			completedJobs = append(completedJobs, job)
 		}
 	}
@@ -125,16 +125,17 @@ func (qm *QueueManager) UpdateJobStatus(jobID string, status api.JobStatus, errM
 	return false
 }
 
-// GetNextJob finds the first job with 'queued' status, marks it as 'processing',
-// and returns it. Returns nil, false if no queued jobs are found.
-// This is a simple FIFO implementation.
+// GetNextJob finds the first job with 'queued' status, or a 'scheduled' job whose
+// start time has arrived, marks it as 'processing', and returns it.
+// Returns nil, false if no jobs are ready. This is a simple FIFO implementation.
 func (qm *QueueManager) GetNextJob() (*api.DownloadJob, bool) {
 	qm.mutex.Lock()
 	defer qm.mutex.Unlock()
 
+	now := time.Now().UTC()
 	for _, job := range qm.jobs {
-		if job.Status == api.StatusQueued {
-			now := time.Now().UTC()
+		due := job.Status == api.StatusScheduled && job.ScheduledFor != nil && !now.Before(*job.ScheduledFor)
+		if job.Status == api.StatusQueued || due {
 			job.Status = api.StatusProcessing
 			job.StartedAt = &now
 			logger.Info("[QueueManager] Picking next job for processing", "jobID", job.ID)
@@ -146,6 +147,26 @@ func (qm *QueueManager) GetNextJob() (*api.DownloadJob, bool) {
 	return nil, false // No queued jobs found
 }
 
+// ScheduleJob parks a job in 'scheduled' status until the given time, after which
+// GetNextJob picks it up again.
+func (qm *QueueManager) ScheduleJob(jobID string, at time.Time) bool {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+
+	for _, job := range qm.jobs {
+		if job.ID == jobID {
+			at = at.UTC()
+			job.Status = api.StatusScheduled
+			job.ScheduledFor = &at
+			job.StartedAt = nil
+			logger.Info("[QueueManager] Job scheduled", "jobID", jobID, "scheduledFor", at)
+			return true
+		}
+	}
+	logger.Warn("[QueueManager] Failed to schedule unknown job ID", "jobID", jobID)
+	return false
+}
+
 // UpdateJobArtwork updates the artwork URL for a specific job.
 func (qm *QueueManager) UpdateJobArtwork(jobID string, artworkURL string) bool {
 	qm.mutex.Lock()
@@ -211,7 +232,7 @@ func (qm *QueueManager) UpdateJobProgress(jobID string, progress float64, speedB
 
 // RemoveJob removes a job from the queue by its ID.
 // Returns true if the job was found and removed, false otherwise.
-// Only allows removal if job is in Queued, Failed, or Complete status.
+// Only allows removal if job is in Queued, Scheduled, Failed, or Complete status.
 func (qm *QueueManager) RemoveJob(jobID string) bool {
 	qm.mutex.Lock()
 	defer qm.mutex.Unlock()
@@ -220,7 +241,7 @@ func (qm *QueueManager) RemoveJob(jobID string) bool {
 	for i, job := range qm.jobs {
 		if job.ID == jobID {
 			// Check if job is in a removable state
-			if job.Status == api.StatusQueued || job.Status == api.StatusFailed || job.Status == api.StatusComplete {
+			if job.Status == api.StatusQueued || job.Status == api.StatusScheduled || job.Status == api.StatusFailed || job.Status == api.StatusComplete {
 				removeIndex = i
 				break
 			} else {
@@ -259,7 +280,125 @@ func (qm *QueueManager) UpdateJobContainerID(jobID string, containerID string) b
 	return false
 }
 
+// UpdateJobOutputPath records the folder a job is writing its files to, or the file
+// for a video.
+func (qm *QueueManager) UpdateJobOutputPath(jobID string, outputPath string) bool {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+
+	for _, job := range qm.jobs {
+		if job.ID == jobID {
+			job.OutputPath = outputPath
+			logger.Debug("[QueueManager] Output path updated for job", "jobID", jobID, "outputPath", outputPath)
+			return true
+		}
+	}
+	logger.Warn("[QueueManager] Failed to update output path for unknown job ID", "jobID", jobID)
+	return false
+}
+
+// UpdateJobVideoVariant records the video variant chosen for a job.
+func (qm *QueueManager) UpdateJobVideoVariant(jobID string, variant *api.VideoVariant) bool {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+
+	for _, job := range qm.jobs {
+		if job.ID == jobID {
+			job.VideoVariant = variant
+			logger.Debug("[QueueManager] Video variant updated for job", "jobID", jobID, "resolution", variant.Resolution, "codec", variant.Codec)
+			return true
+		}
+	}
+	logger.Warn("[QueueManager] Failed to update video variant for unknown job ID", "jobID", jobID)
+	return false
+}
+
+// AddJobSkippedSegment records a live segment a job gave up on.
+func (qm *QueueManager) AddJobSkippedSegment(jobID string, segment string) bool {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+
+	for _, job := range qm.jobs {
+		if job.ID == jobID {
+			job.SkippedSegments = append(job.SkippedSegments, segment)
+			logger.Debug("[QueueManager] Skipped live segment recorded for job", "jobID", jobID, "segment", segment)
+			return true
+		}
+	}
+	logger.Warn("[QueueManager] Failed to record skipped segment for unknown job ID", "jobID", jobID)
+	return false
+}
+
+// AddJobTrackResult appends the outcome of a track to a job and flags the job
+// as degraded if the track fell back from the preferred format.
+func (qm *QueueManager) AddJobTrackResult(jobID string, result api.TrackResult) bool {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+
+	for _, job := range qm.jobs {
+		if job.ID == jobID {
+			job.Tracks = append(job.Tracks, result)
+			if result.Degraded {
+				job.Degraded = true
+			}
+			logger.Debug("[QueueManager] Track result recorded for job", "jobID", jobID, "trackNum", result.TrackNum, "format", result.Format, "degraded", result.Degraded, "error", result.Error)
+			return true
+		}
+	}
+	logger.Warn("[QueueManager] Failed to record track result for unknown job ID", "jobID", jobID)
+	return false
+}
+
+// MarkJobDryRun flags a job as a dry run and clears any plan from a previous attempt.
+func (qm *QueueManager) MarkJobDryRun(jobID string) bool {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+
+	for _, job := range qm.jobs {
+		if job.ID == jobID {
+			job.DryRun = true
+			job.Plan = nil
+			return true
+		}
+	}
+	logger.Warn("[QueueManager] Failed to mark unknown job ID as dry run", "jobID", jobID)
+	return false
+}
+
+// AddJobPlanItem appends a planned file to a dry-run job.
+func (qm *QueueManager) AddJobPlanItem(jobID string, item api.PlanItem) bool {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+
+	for _, job := range qm.jobs {
+		if job.ID == jobID {
+			job.Plan = append(job.Plan, item)
+			logger.Debug("[QueueManager] Plan item recorded for job", "jobID", jobID, "path", item.Path, "kind", item.Kind, "sizeBytes", item.SizeBytes, "exists", item.Exists)
+			return true
+		}
+	}
+	logger.Warn("[QueueManager] Failed to record plan item for unknown job ID", "jobID", jobID)
+	return false
+}
+
+// SetJobEffectiveSettings records the settings a job downloads with.
+func (qm *QueueManager) SetJobEffectiveSettings(jobID string, settings *api.EffectiveSettings) bool {
+	qm.mutex.Lock()
+	defer qm.mutex.Unlock()
+
+	for _, job := range qm.jobs {
+		if job.ID == jobID {
+			job.Effective = settings
+			logger.Debug("[QueueManager] Effective settings recorded for job", "jobID", jobID, "artistID", settings.ArtistID, "artistOverride", settings.ArtistOverride)
+			return true
+		}
+	}
+	logger.Warn("[QueueManager] Failed to record effective settings for unknown job ID", "jobID", jobID)
+	return false
+}
+
 // HasCompletedJobWithContainerID checks if a job with the given ContainerID has already been completed.
+// Dry runs don't count, since they wrote nothing.
 // It returns true and the ID of the completed job if found, otherwise false and an empty string.
 func (qm *QueueManager) HasCompletedJobWithContainerID(containerID string) (bool, string) {
 	qm.mutex.RLock()
@@ -270,7 +409,7 @@ func (qm *QueueManager) HasCompletedJobWithContainerID(containerID string) (bool
 	}
 
 	for _, job := range qm.jobs {
-		if job.ContainerID == containerID && job.Status == api.StatusComplete {
+		if job.ContainerID == containerID && job.Status == api.StatusComplete && !job.DryRun {
 			logger.Info("[QueueManager] Found existing completed job with matching ContainerID", "checkedContainerID", containerID, "foundJobID", job.ID)
 			return true, job.ID
 		}
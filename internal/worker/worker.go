@@ -11,8 +11,16 @@ import (
 	"nugs-dl/pkg/api"
 )
 
+// JobNotifier is told about jobs that finished, whether they completed or failed.
+// It is called in its own goroutine with a copy of the job.
+type JobNotifier interface {
+	JobFinished(job *api.DownloadJob)
+}
+
 // StartWorker launches a background goroutine to process jobs from the queue.
-func StartWorker(qm *queue.QueueManager, dl *downloader.Downloader, hub *broadcast.Hub) {
+// Jobs run one at a time, except live captures, which can take hours and run in their
+// own goroutine so the rest of the queue keeps moving. The notifier is optional.
+func StartWorker(qm *queue.QueueManager, dl *downloader.Downloader, hub *broadcast.Hub, notifier JobNotifier) {
 	logger.Info("[Worker] Starting background queue processor...")
 
 	go func() {
@@ -26,59 +34,81 @@ func StartWorker(qm *queue.QueueManager, dl *downloader.Downloader, hub *broadca
 				continue
 			}
 
-			logger.Info("[Worker] Processing job", "jobID", job.ID, "url", job.OriginalUrl)
-
-			// Execute the download logic
-			// Pass the whole job object to the Download method
-			err := dl.Download(job)
-
-			// Update job status based on the result
-			if err != nil {
-				if errors.Is(err, downloader.ErrDuplicateCompleted) {
-					logger.Info("[Worker] Job is a duplicate of already completed content, skipping.", "jobID", job.ID, "originalError", err.Error())
-					// Update job status to Failed with the specific duplicate error message
-					qm.UpdateJobStatus(job.ID, api.StatusFailed, err.Error()) // err.Error() will contain the formatted message
-
-					job.Status = api.StatusFailed
-					job.ErrorMessage = err.Error() // Use the detailed error message
-					now := time.Now().UTC()
-					if job.CompletedAt == nil { // Mark as "completed" in terms of processing attempt
-						job.CompletedAt = &now
-					}
-					logger.Debug("[Worker] Broadcasting skipped (duplicate) job status", "jobID", job.ID)
-					hub.BroadcastJobStatusUpdate(job) // Broadcast the update
-				} else {
-					// Handle other general errors
-					logger.Error("[Worker] Job failed with a general error", "jobID", job.ID, "error", err)
-					qm.UpdateJobStatus(job.ID, api.StatusFailed, err.Error())
-					// Update the job object directly and broadcast
-					job.Status = api.StatusFailed
-					job.ErrorMessage = err.Error()
-					now := time.Now().UTC()
-					if job.CompletedAt == nil {
-						job.CompletedAt = &now
-					}
-					logger.Debug("[Worker] Broadcasting failed job status", "jobID", job.ID)
-					hub.BroadcastJobStatusUpdate(job)
-				}
-			} else {
-				// Handle successful completion
-				logger.Info("[Worker] Job completed successfully", "jobID", job.ID)
-				qm.UpdateJobStatus(job.ID, api.StatusComplete, "")
-				// Update the job object directly and broadcast
-				job.Status = api.StatusComplete
-				job.Progress = 100 // Ensure progress is 100%
-				job.ErrorMessage = ""
-				now := time.Now().UTC()
-				if job.CompletedAt == nil {
-					job.CompletedAt = &now
-				}
-				logger.Debug("[Worker] Broadcasting completed job status", "jobID", job.ID)
-				hub.BroadcastJobStatusUpdate(job)
+			if downloader.IsLiveCaptureJob(job) {
+				logger.Info("[Worker] Running live capture alongside the queue", "jobID", job.ID, "url", job.OriginalUrl)
+				go runJob(qm, dl, hub, notifier, job)
+				continue
 			}
+			runJob(qm, dl, hub, notifier, job)
 
 			// Optional short delay between processing jobs?
 			// time.Sleep(1 * time.Second)
 		}
 	}() // Launch the goroutine
 }
+
+// runJob downloads a job and records and broadcasts its outcome.
+func runJob(qm *queue.QueueManager, dl *downloader.Downloader, hub *broadcast.Hub, notifier JobNotifier, job *api.DownloadJob) {
+	logger.Info("[Worker] Processing job", "jobID", job.ID, "url", job.OriginalUrl)
+
+	// Execute the download logic
+	// Pass the whole job object to the Download method
+	err := dl.Download(job)
+
+	// Update job status based on the result
+	if err != nil {
+		if errors.Is(err, downloader.ErrScheduled) {
+			// The downloader parked the job until its livestream starts; GetNextJob picks it up again then
+			logger.Info("[Worker] Job scheduled for later", "jobID", job.ID, "scheduledFor", job.ScheduledFor)
+			hub.BroadcastJobStatusUpdate(job)
+		} else if errors.Is(err, downloader.ErrDuplicateCompleted) {
+			logger.Info("[Worker] Job is a duplicate of already completed content, skipping.", "jobID", job.ID, "originalError", err.Error())
+			// Update job status to Failed with the specific duplicate error message
+			qm.UpdateJobStatus(job.ID, api.StatusFailed, err.Error()) // err.Error() will contain the formatted message
+
+			job.Status = api.StatusFailed
+			job.ErrorMessage = err.Error() // Use the detailed error message
+			now := time.Now().UTC()
+			if job.CompletedAt == nil { // Mark as "completed" in terms of processing attempt
+				job.CompletedAt = &now
+			}
+			logger.Debug("[Worker] Broadcasting skipped (duplicate) job status", "jobID", job.ID)
+			hub.BroadcastJobStatusUpdate(job) // Broadcast the update
+		} else {
+			// Handle other general errors
+			logger.Error("[Worker] Job failed with a general error", "jobID", job.ID, "error", err)
+			qm.UpdateJobStatus(job.ID, api.StatusFailed, err.Error())
+			// Update the job object directly and broadcast
+			job.Status = api.StatusFailed
+			job.ErrorMessage = err.Error()
+			now := time.Now().UTC()
+			if job.CompletedAt == nil {
+				job.CompletedAt = &now
+			}
+			logger.Debug("[Worker] Broadcasting failed job status", "jobID", job.ID)
+			hub.BroadcastJobStatusUpdate(job)
+			if notifier != nil {
+				finished := *job // Sent in the background, so the next job isn't held up
+				go notifier.JobFinished(&finished)
+			}
+		}
+	} else {
+		// Handle successful completion
+		logger.Info("[Worker] Job completed successfully", "jobID", job.ID)
+		qm.UpdateJobStatus(job.ID, api.StatusComplete, "")
+		// Update the job object directly and broadcast
+		job.Status = api.StatusComplete
+		job.Progress = 100 // Ensure progress is 100%
+		job.ErrorMessage = ""
+		now := time.Now().UTC()
+		if job.CompletedAt == nil {
+			job.CompletedAt = &now
+		}
+		logger.Debug("[Worker] Broadcasting completed job status", "jobID", job.ID)
+		hub.BroadcastJobStatusUpdate(job)
+		if notifier != nil {
+			finished := *job // Sent in the background, so the next job isn't held up
+			go notifier.JobFinished(&finished)
+		}
+	}
+}
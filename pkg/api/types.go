@@ -14,6 +14,7 @@ const (
 	StatusProcessing JobStatus = "processing"
 	StatusComplete   JobStatus = "complete"
 	StatusFailed     JobStatus = "failed"
+	StatusScheduled  JobStatus = "scheduled" // Waiting for a livestream to start
 )
 
 // DownloadOptions mirrors the options needed by the downloader.
@@ -22,9 +23,74 @@ type DownloadOptions struct {
 	ForceVideo   bool `json:"forceVideo"`
 	SkipVideos   bool `json:"skipVideos"`
 	SkipChapters bool `json:"skipChapters"`
+	// Track layout mode: sequential, disc-folders, disc-track or set-prefixed (empty uses config)
+	Layout string `json:"layout,omitempty"`
+	// Subset of a release's tracks to download (nil downloads every non-excluded track)
+	Selection *TrackSelection `json:"selection,omitempty"`
+	// Ordered audio format preference (1: ALAC, 2: FLAC, 3: MQA, 4: 360RA, 5: AAC); empty uses config
+	Formats []int `json:"formats,omitempty"`
+	// Fail tracks rather than degrade outside the preference chain; nil uses config
+	StrictQuality *bool `json:"strictQuality,omitempty"`
+	// Additional formats downloaded alongside the primary one, each into its own folder
+	ExtraFormats []int `json:"extraFormats,omitempty"`
+	// Replace existing lossy copies of tracks with the lossless format downloaded
+	ReplaceLossy bool `json:"replaceLossy,omitempty"`
+	// Render the release as one file with chapters: "alongside" or "replace" (empty uses config)
+	SingleFile string `json:"singleFile,omitempty"`
+	// Cut videos at their chapters into per-song audio tracks and/or MP4 clips
+	SplitChapters bool `json:"splitChapters,omitempty"`
+	SplitClips    bool `json:"splitClips,omitempty"`
+	// Also write the full audio of videos as one file into the audio library
+	ExtractAudio bool `json:"extractAudio,omitempty"`
+	// Only plan the download: resolve metadata, formats and variants without writing media
+	DryRun bool `json:"dryRun,omitempty"`
+	// Overrides of the configured format, video format and output root (0/empty uses config)
+	Format      int    `json:"format,omitempty"`
+	VideoFormat int    `json:"videoFormat,omitempty"`
+	OutPath     string `json:"outPath,omitempty"`
+	// Releases of an artist URL to download (nil downloads them all)
+	ArtistFilter *ArtistFilter `json:"artistFilter,omitempty"`
 	// Add format overrides if needed
 }
 
+// ArtistFilter picks releases of an artist job. All given criteria must match.
+type ArtistFilter struct {
+	DateFrom    string `json:"dateFrom,omitempty"`    // Earliest performance date, YYYY-MM-DD
+	DateTo      string `json:"dateTo,omitempty"`      // Latest performance date, YYYY-MM-DD
+	Year        int    `json:"year,omitempty"`        // Performance year
+	Media       string `json:"media,omitempty"`       // "audio", "video" or "both" (empty is both)
+	Venue       string `json:"venue,omitempty"`       // Case-insensitive substring of venue, city or state
+	OnlyMissing bool   `json:"onlyMissing,omitempty"` // Skip releases already in the library
+}
+
+// ArtistFilterRequest asks how many releases of an artist a filter would download.
+type ArtistFilterRequest struct {
+	ArtistID string        `json:"artistId,omitempty"`
+	Url      string        `json:"url,omitempty"` // Artist URL, used when artistId is empty
+	Filter   *ArtistFilter `json:"filter,omitempty"`
+	OutPath  string        `json:"outPath,omitempty"` // Output root checked by onlyMissing (empty uses config)
+}
+
+// ArtistFilterPreview is what an artist job with a filter would download.
+type ArtistFilterPreview struct {
+	ArtistID   string             `json:"artistId"`
+	ArtistName string             `json:"artistName"`
+	Total      int                `json:"total"`      // Releases of the artist
+	Matched    int                `json:"matched"`    // Releases that would be downloaded
+	InLibrary  int                `json:"inLibrary"`  // Matching releases skipped because they're in the library
+	Containers []ContainerSummary `json:"containers"` // The releases that would be downloaded
+}
+
+// TrackSelection picks tracks within a release. Criteria are combined as a union;
+// when none are given every track is selected. Tracks flagged trackExclude in the
+// metadata are skipped unless IncludeExcluded is set.
+type TrackSelection struct {
+	Tracks          []string `json:"tracks,omitempty"`          // 1-based indices or ranges, e.g. "3", "5-8"
+	Sets            []int    `json:"sets,omitempty"`            // Set numbers, e.g. 2 for the second set
+	Titles          []string `json:"titles,omitempty"`          // Case-insensitive substrings, or /regex/
+	IncludeExcluded bool     `json:"includeExcluded,omitempty"` // Also download tracks marked trackExclude
+}
+
 // DownloadJob represents a single download task in the queue.
 type DownloadJob struct {
 	ID           string          `json:"id"`                     // Unique identifier (e.g., UUID)
@@ -36,14 +102,76 @@ type DownloadJob struct {
 	CreatedAt    time.Time       `json:"createdAt"`              // Timestamp when the job was added
 	StartedAt    *time.Time      `json:"startedAt,omitempty"`    // Timestamp when processing started
 	CompletedAt  *time.Time      `json:"completedAt,omitempty"`  // Timestamp when completed or failed
+	ScheduledFor *time.Time      `json:"scheduledFor,omitempty"` // When a scheduled livestream capture begins
 	Progress     float64         `json:"progress"`               // Overall progress percentage (0-100)
 	CurrentFile  string          `json:"currentFile,omitempty"`  // Name of the file currently being downloaded/processed
 	SpeedBPS     int64           `json:"speedBps"`               // Current download speed in Bytes per second
 	ArtworkURL   string          `json:"artworkUrl,omitempty"`   // URL for album/video artwork
 	ContainerID  string          `json:"containerId,omitempty"`  // Unique identifier for the album/show (e.g., nugs.net containerID)
+	OutputPath   string          `json:"outputPath,omitempty"`   // Folder the job's files are written to, or the file for videos (rendered from path templates)
 	// Track information
 	CurrentTrack int            `json:"currentTrack,omitempty"` // Current track number (1-based)
 	TotalTracks  int            `json:"totalTracks,omitempty"`  // Total number of tracks
+	// Per-track outcome, including the format actually chosen
+	Tracks   []TrackResult `json:"tracks,omitempty"`
+	Degraded bool          `json:"degraded,omitempty"` // At least one track fell back from the preferred format
+	// Properties of the video variant chosen for video jobs
+	VideoVariant *VideoVariant `json:"videoVariant,omitempty"`
+	// Live segments that kept failing and were left out of the recording
+	SkippedSegments []string `json:"skippedSegments,omitempty"`
+	// Dry runs record the files they would create instead of downloading them
+	DryRun bool       `json:"dryRun,omitempty"`
+	Plan   []PlanItem `json:"plan,omitempty"`
+	// Settings the job ran with after merging job options, artist overrides and globals
+	Effective *EffectiveSettings `json:"effective,omitempty"`
+}
+
+// EffectiveSettings are the settings a job downloads a release with.
+type EffectiveSettings struct {
+	ArtistID       int    `json:"artistId,omitempty"`
+	ArtistName     string `json:"artistName,omitempty"`
+	ArtistOverride bool   `json:"artistOverride"` // The artist has overrides in the config
+	Formats        []int  `json:"formats"`        // Audio format preference chain
+	VideoFormat    int    `json:"videoFormat"`
+	OutPath        string `json:"outPath"`
+	Notifications  bool   `json:"notifications"`
+}
+
+// PlanItem is a file a dry run would create.
+type PlanItem struct {
+	Path       string `json:"path"`
+	Kind       string `json:"kind"`                 // track, video, single or transcode
+	Format     int    `json:"format,omitempty"`     // Audio format code of tracks
+	FormatName string `json:"formatName,omitempty"` // e.g. "FLAC", "1080p", or the transcode profile name
+	Specs      string `json:"specs,omitempty"`
+	SizeBytes  int64  `json:"sizeBytes"` // Estimated size, -1 if unknown
+	Exists     bool   `json:"exists"`    // The file is already on disk and would be skipped
+}
+
+// VideoVariant records the properties of the video stream chosen for a job.
+type VideoVariant struct {
+	Resolution string  `json:"resolution"` // e.g. "1080p", "4K"
+	Width      int     `json:"width,omitempty"`
+	Height     int     `json:"height,omitempty"`
+	Codec      string  `json:"codec,omitempty"`  // h264, hevc, or the raw CODECS value
+	Codecs     string  `json:"codecs,omitempty"` // Raw HLS CODECS attribute
+	FrameRate  float64 `json:"frameRate,omitempty"`
+	Bandwidth  uint32  `json:"bandwidth,omitempty"` // bits/s
+}
+
+// TrackResult records the outcome of a single track within a job.
+type TrackResult struct {
+	TrackNum   int    `json:"trackNum"`
+	Title      string `json:"title"`
+	Format     int    `json:"format,omitempty"`     // Format code actually downloaded
+	FormatName string `json:"formatName,omitempty"` // e.g. "FLAC"
+	Specs      string `json:"specs,omitempty"`      // e.g. "16-bit / 44.1 kHz FLAC"
+	Wanted     int    `json:"wanted,omitempty"`     // First format in the preference chain
+	Degraded   bool   `json:"degraded,omitempty"`   // Format differs from Wanted
+	Path       string `json:"path,omitempty"`
+	Error      string `json:"error,omitempty"` // Set if the track failed
+	Extra      bool   `json:"extra,omitempty"` // A copy in one of the job's extra formats
+	Existing   bool   `json:"existing,omitempty"` // The file was already there and was kept
 }
 
 // AddDownloadRequest is the expected request body for adding new download jobs.
@@ -73,6 +201,254 @@ type ProgressUpdate struct {
 	// Track-based progress information
 	CurrentTrack    int       `json:"currentTrack,omitempty"` // Current track number (1-based)
 	TotalTracks     int       `json:"totalTracks,omitempty"`  // Total number of tracks
+	// Media time recorded so far during live capture
+	ElapsedSeconds  float64   `json:"elapsedSeconds,omitempty"`
+	// Processing phase, e.g. "transcode" (empty while downloading)
+	Phase           string    `json:"phase,omitempty"`
+}
+
+// TemplatePreviewRequest is the request body for rendering a path template against real metadata.
+type TemplatePreviewRequest struct {
+	Kind        string `json:"kind" binding:"required,oneof=album track playlist video"`
+	Template    string `json:"template" binding:"required"`
+	ContainerID string `json:"containerId,omitempty"` // Container to render against
+	Url         string `json:"url,omitempty"`         // Alternatively, a release URL
+}
+
+// TemplatePreviewResponse holds the paths rendered by a template preview.
+type TemplatePreviewResponse struct {
+	Kind        string   `json:"kind"`
+	Template    string   `json:"template"`
+	ContainerID string   `json:"containerId"`
+	Paths       []string `json:"paths"` // Relative paths; one per track for track templates
+}
+
+// TrackPreviewRequest is the request body for listing a release's tracks before enqueueing.
+type TrackPreviewRequest struct {
+	ContainerID string          `json:"containerId,omitempty"`
+	Url         string          `json:"url,omitempty"`
+	Selection   *TrackSelection `json:"selection,omitempty"`
+	Layout      string          `json:"layout,omitempty"`
+}
+
+// TrackPreview describes one track of a release in a preview listing.
+type TrackPreview struct {
+	Index           int    `json:"index"`  // 1-based position in the release, as used by TrackSelection.Tracks
+	Number          string `json:"number"` // Number as it would appear in the filename, e.g. "d2t03"
+	DiscNum         int    `json:"discNum"`
+	SetNum          int    `json:"setNum"`
+	Title           string `json:"title"`
+	DurationSeconds int    `json:"durationSeconds"`
+	Excluded        bool   `json:"excluded"` // Marked trackExclude in the metadata
+	Selected        bool   `json:"selected"` // Would be downloaded with the given selection
+}
+
+// TrackListPreview is the response of a track preview request.
+type TrackListPreview struct {
+	ContainerID   int            `json:"containerId"`
+	ArtistName    string         `json:"artistName"`
+	Title         string         `json:"title"`
+	SelectedCount int            `json:"selectedCount"`
+	Tracks        []TrackPreview `json:"tracks"`
+}
+
+// URLPreviewRequest is the request body for inspecting URLs before enqueueing them.
+type URLPreviewRequest struct {
+	Urls []string `json:"urls" binding:"required"`
+}
+
+// URLPreview describes what a URL points to, without creating a job.
+type URLPreview struct {
+	Url         string `json:"url"`
+	ResolvedUrl string `json:"resolvedUrl,omitempty"` // Target of a 2nu.gs short link
+	Type        string `json:"type"`                  // release, video, livestream, webcast, purchased, playlist, artist or unknown
+	ID          string `json:"id,omitempty"`
+	ContainerID int    `json:"containerId,omitempty"`
+	Title       string `json:"title,omitempty"`
+	ArtistName  string `json:"artistName,omitempty"`
+	Date        string `json:"date,omitempty"` // YYYY-MM-DD when known
+	Venue       string `json:"venue,omitempty"`
+	ArtworkURL  string `json:"artworkUrl,omitempty"`
+	// Tracks of a release or playlist
+	Tracks   []TrackPreview `json:"tracks,omitempty"`
+	HasVideo bool           `json:"hasVideo"`
+	Products []ProductSKU   `json:"products,omitempty"` // SKUs offered for the container
+	// Containers of an artist page
+	ContainerCount int                `json:"containerCount,omitempty"`
+	Containers     []ContainerSummary `json:"containers,omitempty"`
+	// Library state: already downloaded by a completed job or present on disk
+	InLibrary      bool     `json:"inLibrary"`
+	LibraryPath    string   `json:"libraryPath,omitempty"`
+	CompletedJobID string   `json:"completedJobId,omitempty"`
+	Warnings       []string `json:"warnings,omitempty"`
+	Error          string   `json:"error,omitempty"` // Set if the URL couldn't be previewed
+}
+
+// ProductSKU is a purchasable or streamable format of a container.
+type ProductSKU struct {
+	SkuID     int    `json:"skuId"`
+	FormatStr string `json:"formatStr"`
+	PfType    int    `json:"pfType,omitempty"`
+}
+
+// ContainerSummary is a short description of a container in a listing.
+type ContainerSummary struct {
+	ContainerID int    `json:"containerId"`
+	Title       string `json:"title"`
+	Date        string `json:"date,omitempty"`
+	Venue       string `json:"venue,omitempty"`
+	InLibrary   bool   `json:"inLibrary"` // Downloaded by a completed job or present on disk
+}
+
+// Library states of a catalog item.
+const (
+	CatalogOwned    = "owned"     // Downloaded by a completed job or found in the library
+	CatalogQueued   = "queued"    // A queued, scheduled or running job will download it
+	CatalogNotOwned = "not_owned" // Neither
+)
+
+// CatalogItem is a release in an artist's catalog.
+type CatalogItem struct {
+	ContainerID int    `json:"containerId"`
+	Title       string `json:"title"`
+	Date        string `json:"date,omitempty"`
+	Venue       string `json:"venue,omitempty"`
+	Type        string `json:"type,omitempty"` // Container type, e.g. "Show" or "Video"
+	ArtworkURL  string `json:"artworkUrl,omitempty"`
+	HasVideo    bool   `json:"hasVideo"`
+	State       string `json:"state"`           // owned, queued or not_owned
+	JobID       string `json:"jobId,omitempty"` // Job that downloaded or will download it
+}
+
+// ArtistCatalog is a page of an artist's catalog.
+type ArtistCatalog struct {
+	ArtistID   string        `json:"artistId"`
+	ArtistName string        `json:"artistName"`
+	Total      int           `json:"total"` // Releases matching the search
+	Page       int           `json:"page"`
+	PageSize   int           `json:"pageSize"`
+	Items      []CatalogItem `json:"items"`
+}
+
+// CatalogEnqueueRequest queues downloads of releases picked from an artist's catalog.
+type CatalogEnqueueRequest struct {
+	ContainerIDs []int           `json:"containerIds" binding:"required"`
+	Options      DownloadOptions `json:"options"`
+}
+
+// Kinds of gaps in a collection.
+const (
+	GapMissing       = "missing"        // The release isn't in the library
+	GapLowerQuality  = "lower_quality"  // Owned in a lossy format while a lossless one is wanted, or degraded
+	GapMissingTracks = "missing_tracks" // Owned with fewer tracks than the release has
+)
+
+// GapItem is a release of an artist with gaps in the collection.
+type GapItem struct {
+	ContainerID    int      `json:"containerId"`
+	Title          string   `json:"title"`
+	Date           string   `json:"date,omitempty"`
+	Venue          string   `json:"venue,omitempty"`
+	Url            string   `json:"url"`
+	Gaps           []string `json:"gaps"`                 // missing, lower_quality and/or missing_tracks
+	JobID          string   `json:"jobId,omitempty"`      // Completed job that downloaded it
+	Path           string   `json:"path,omitempty"`       // Library folder found
+	FormatName     string   `json:"formatName,omitempty"` // Format owned, if known
+	ExpectedTracks int      `json:"expectedTracks,omitempty"`
+	HaveTracks     int      `json:"haveTracks,omitempty"`
+	MissingTracks  []string `json:"missingTracks,omitempty"` // Track indices to fetch again, e.g. "3" or "1-12"
+	// MissingTracksUnknown is set when the library files couldn't be matched to tracks,
+	// so which tracks are missing isn't known.
+	MissingTracksUnknown bool `json:"missingTracksUnknown,omitempty"`
+}
+
+// GapReport compares an artist's catalog with the library and download history.
+type GapReport struct {
+	ArtistID        string    `json:"artistId"`
+	ArtistName      string    `json:"artistName"`
+	GeneratedAt     time.Time `json:"generatedAt"`
+	WantedFormat    string    `json:"wantedFormat"` // Format owned releases are compared against
+	Total           int       `json:"total"`        // Releases in the catalog
+	Owned           int       `json:"owned"`
+	Missing         int       `json:"missing"`
+	LowerQuality    int       `json:"lowerQuality"`
+	Incomplete      int       `json:"incomplete"` // Releases with missing tracks
+	Items           []GapItem `json:"items"`      // Releases with at least one gap
+}
+
+// GapEnqueueRequest queues downloads that fill the gaps of an artist's collection.
+type GapEnqueueRequest struct {
+	Gaps    []string        `json:"gaps,omitempty"` // Kinds of gaps to fill; empty fills only missing releases
+	Options DownloadOptions `json:"options"`
+}
+
+// MonitorStatus describes the artist monitor and the artists it watches.
+type MonitorStatus struct {
+	Enabled       bool                  `json:"enabled"` // Periodic checks are on
+	IntervalHours int                   `json:"intervalHours"`
+	Checking      bool                  `json:"checking"` // A check is running right now
+	Artists       []MonitorArtistStatus `json:"artists"`
+}
+
+// MonitorArtistStatus is the monitor state of one configured artist.
+type MonitorArtistStatus struct {
+	ID            int           `json:"id"`
+	Name          string        `json:"name"`
+	Enabled       bool          `json:"enabled"`
+	IntervalHours int           `json:"intervalHours"`
+	LastCheck     *time.Time    `json:"lastCheck,omitempty"`
+	NextCheck     *time.Time    `json:"nextCheck,omitempty"`
+	LastError     string        `json:"lastError,omitempty"`
+	KnownReleases int           `json:"knownReleases"`
+	Found         []MonitorFind `json:"found,omitempty"` // Most recent first
+}
+
+// MonitorFind is a new release found by the monitor.
+type MonitorFind struct {
+	ContainerID int       `json:"containerId"`
+	Title       string    `json:"title"`
+	Date        string    `json:"date,omitempty"`
+	FoundAt     time.Time `json:"foundAt"`
+	JobID       string    `json:"jobId,omitempty"` // Job queued for it
+	Skipped     string    `json:"skipped,omitempty"` // Why it wasn't queued, e.g. already in the library
+	Error       string    `json:"error,omitempty"`   // Queueing failed
+}
+
+// FormatReport lists the audio qualities offered for each track of a release.
+type FormatReport struct {
+	ContainerID int             `json:"containerId"`
+	ArtistName  string          `json:"artistName"`
+	Title       string          `json:"title"`
+	ProbedAt    time.Time       `json:"probedAt"`
+	Cached      bool            `json:"cached"` // Served from the per-container cache
+	Summary     []FormatSummary `json:"summary"`
+	Tracks      []TrackFormats  `json:"tracks"`
+}
+
+// FormatSummary counts the tracks of a release offered in one format.
+type FormatSummary struct {
+	Format     int    `json:"format"`
+	FormatName string `json:"formatName"`
+	Tracks     int    `json:"tracks"`
+	TotalBytes int64  `json:"totalBytes"` // Sum of the known sizes
+}
+
+// TrackFormats lists the formats offered for one track.
+type TrackFormats struct {
+	Index   int               `json:"index"` // 1-based position in the release
+	TrackID int               `json:"trackId"`
+	Title   string            `json:"title"`
+	HlsOnly bool              `json:"hlsOnly"`
+	Formats []FormatAvailable `json:"formats"`
+	Error   string            `json:"error,omitempty"` // Set if the track couldn't be probed
+}
+
+// FormatAvailable is one format offered for a track.
+type FormatAvailable struct {
+	Format     int    `json:"format"` // 1: ALAC, 2: FLAC, 3: MQA, 4: 360RA, 5: AAC, 6: HLS-only AAC
+	FormatName string `json:"formatName"`
+	Specs      string `json:"specs"`
+	SizeBytes  int64  `json:"sizeBytes"` // -1 if unknown
 }
 
 // --- SSE Event Structure ---
@@ -156,6 +156,7 @@ export function QueueDisplay() {
     switch (status) {
       case "processing": return "default";
       case "queued": return "secondary";
+      case "scheduled": return "secondary";
       case "complete": return "outline";
       case "failed": return "destructive";
       default: return "secondary";
@@ -259,7 +260,7 @@ export function QueueDisplay() {
                 </TableRow>
               ) : (
                 jobList.map((job) => {
-                  const isRemovable = job.status === 'queued' || job.status === 'failed' || job.status === 'complete';
+                  const isRemovable = job.status === 'queued' || job.status === 'scheduled' || job.status === 'failed' || job.status === 'complete';
                   const isCurrentlyRemoving = removingJobId === job.id;
                   return (
                     <TableRow key={job.id}>
@@ -108,7 +108,7 @@ const QueueManager = ({ jobs, isLoading, error, onAddDownload }: QueueManagerPro
     return {
       all: jobList.length,
       downloading: jobList.filter(job => job.status === 'processing').length,
-      queued: jobList.filter(job => job.status === 'queued').length,
+      queued: jobList.filter(job => job.status === 'queued' || job.status === 'scheduled').length,
       paused: 0, // Not implemented in backend yet
       error: jobList.filter(job => job.status === 'failed').length,
       completed: jobList.filter(job => job.status === 'complete').length
@@ -144,6 +144,7 @@ const QueueManager = ({ jobs, isLoading, error, onAddDownload }: QueueManagerPro
     const statusMap: Record<string, string> = {
       'processing': 'downloading',
       'queued': 'queued', 
+      'scheduled': 'queued',
       'complete': 'completed',
       'failed': 'error'
     }
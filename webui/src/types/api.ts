@@ -5,7 +5,8 @@ export type JobStatus =
   | 'queued'
   | 'processing'
   | 'complete'
-  | 'failed';
+  | 'failed'
+  | 'scheduled'; // Waiting for a livestream to start
 
 export interface AppConfig {
   email: string;
@@ -17,10 +18,171 @@ export interface AppConfig {
   useFfmpegEnvVar: boolean;
 }
 
+export type TrackLayout = 'sequential' | 'disc-folders' | 'disc-track' | 'set-prefixed';
+
 export interface DownloadOptions {
   forceVideo: boolean;
   skipVideos: boolean;
   skipChapters: boolean;
+  layout?: TrackLayout;
+  selection?: TrackSelection;
+  formats?: number[];         // Ordered format preference
+  strictQuality?: boolean;
+  extraFormats?: number[];    // Additional formats, each into its own folder
+  replaceLossy?: boolean;     // Replace existing lossy copies of tracks with the lossless download
+  singleFile?: 'alongside' | 'replace'; // Render the release as one file with chapters
+  splitChapters?: boolean;    // Cut videos into per-song audio tracks
+  splitClips?: boolean;       // Cut videos into per-song MP4 clips
+  extractAudio?: boolean;     // Write the full audio of videos into the audio library
+  dryRun?: boolean;           // Only plan the download, without writing media
+  format?: number;            // Overrides the configured audio format
+  videoFormat?: number;       // Overrides the configured video format
+  outPath?: string;           // Overrides the configured output path
+  artistFilter?: ArtistFilter; // Releases of an artist URL to download
+}
+
+export interface ArtistFilter {
+  dateFrom?: string;          // YYYY-MM-DD
+  dateTo?: string;            // YYYY-MM-DD
+  year?: number;
+  media?: 'audio' | 'video' | 'both';
+  venue?: string;             // Substring of venue, city or state
+  onlyMissing?: boolean;      // Skip releases already in the library
+}
+
+export interface ArtistFilterRequest {
+  artistId?: string;
+  url?: string;               // Artist URL, used when artistId is empty
+  filter?: ArtistFilter;
+  outPath?: string;
+}
+
+export interface ArtistFilterPreview {
+  artistId: string;
+  artistName: string;
+  total: number;
+  matched: number;
+  inLibrary: number;          // Matching releases skipped as already downloaded
+  containers: ContainerSummary[];
+}
+
+export interface TrackResult {
+  trackNum: number;
+  title: string;
+  format?: number;
+  formatName?: string;
+  specs?: string;
+  wanted?: number;
+  degraded?: boolean;
+  path?: string;
+  error?: string;
+  extra?: boolean; // A copy in one of the job's extra formats
+  existing?: boolean; // The file was already there and was kept
+}
+
+export interface TrackSelection {
+  tracks?: string[];          // 1-based indices or ranges, e.g. "3", "5-8"
+  sets?: number[];
+  titles?: string[];          // Substrings, or /regex/
+  includeExcluded?: boolean;
+}
+
+export interface TrackPreview {
+  index: number;
+  number: string;
+  discNum: number;
+  setNum: number;
+  title: string;
+  durationSeconds: number;
+  excluded: boolean;
+  selected: boolean;
+}
+
+export interface TrackListPreview {
+  containerId: number;
+  artistName: string;
+  title: string;
+  selectedCount: number;
+  tracks: TrackPreview[];
+}
+
+export interface URLPreview {
+  url: string;
+  resolvedUrl?: string;  // Target of a 2nu.gs short link
+  type: 'release' | 'video' | 'livestream' | 'webcast' | 'purchased' | 'playlist' | 'artist' | 'unknown';
+  id?: string;
+  containerId?: number;
+  title?: string;
+  artistName?: string;
+  date?: string;
+  venue?: string;
+  artworkUrl?: string;
+  tracks?: TrackPreview[];
+  hasVideo: boolean;
+  products?: ProductSKU[];
+  containerCount?: number;        // Artist pages
+  containers?: ContainerSummary[];
+  inLibrary: boolean;
+  libraryPath?: string;
+  completedJobId?: string;
+  warnings?: string[];
+  error?: string;
+}
+
+export interface ProductSKU {
+  skuId: number;
+  formatStr: string;
+  pfType?: number;
+}
+
+export interface ContainerSummary {
+  containerId: number;
+  title: string;
+  date?: string;
+  venue?: string;
+}
+
+export interface FormatReport {
+  containerId: number;
+  artistName: string;
+  title: string;
+  probedAt: string;
+  cached: boolean;          // Served from the per-container cache
+  summary: FormatSummary[];
+  tracks: TrackFormats[];
+}
+
+export interface FormatSummary {
+  format: number;
+  formatName: string;
+  tracks: number;           // Tracks offered in this format
+  totalBytes: number;       // Sum of the known sizes
+}
+
+export interface TrackFormats {
+  index: number;
+  trackId: number;
+  title: string;
+  hlsOnly: boolean;
+  formats: FormatAvailable[];
+  error?: string;
+}
+
+export interface FormatAvailable {
+  format: number;           // 1: ALAC, 2: FLAC, 3: MQA, 4: 360RA, 5: AAC, 6: HLS-only AAC
+  formatName: string;
+  specs: string;
+  sizeBytes: number;        // -1 if unknown
+}
+
+export interface EffectiveSettings {
+  artistId?: number;
+  artistName?: string;
+  artistOverride: boolean;  // The artist has overrides in the config
+  formats: number[];        // Audio format preference chain
+  videoFormat: number;
+  outPath: string;
+  notifications: boolean;
 }
 
 export interface DownloadJob {
@@ -33,13 +195,23 @@ export interface DownloadJob {
   createdAt: string;       
   startedAt?: string;      
   completedAt?: string;    
+  scheduledFor?: string;  // When a scheduled livestream capture begins
   progress: number;       
   currentFile?: string;   
   speedBps: number;       
   artworkUrl?: string;
+  containerId?: string;
+  outputPath?: string;   // Folder rendered from the path templates (the file for videos)
   // Track information
   currentTrack?: number;
   totalTracks?: number;
+  tracks?: TrackResult[];
+  degraded?: boolean;
+  videoVariant?: VideoVariant; // Video stream chosen for video jobs
+  skippedSegments?: string[]; // Live segments left out of the recording after repeated failures
+  dryRun?: boolean;
+  plan?: PlanItem[];           // Files a dry run would create
+  effective?: EffectiveSettings; // Settings after merging job options, artist overrides and globals
 
   // Fields apparently returned by /api/downloads/history but missing in type def
   type?: 'album' | 'video' | 'livestream' | 'playlist'; // From HistoryItemProps
@@ -49,6 +221,26 @@ export interface DownloadJob {
   format?: string; // e.g., "FLAC", "MP4"
 }
 
+export interface PlanItem {
+  path: string;
+  kind: 'track' | 'video' | 'single' | 'transcode' | 'sidecar' | 'audio' | 'clip';
+  format?: number;
+  formatName?: string;
+  specs?: string;
+  sizeBytes: number;   // Estimated, -1 if unknown
+  exists: boolean;     // Already on disk, would be skipped
+}
+
+export interface VideoVariant {
+  resolution: string;  // e.g. "1080p", "4K"
+  width?: number;
+  height?: number;
+  codec?: string;      // h264, hevc, or the raw CODECS value
+  codecs?: string;
+  frameRate?: number;
+  bandwidth?: number;  // bits/s
+}
+
 export interface AddDownloadRequest {
   urls: string[];
   options: DownloadOptions;
@@ -72,6 +264,8 @@ export interface ProgressUpdate {
   // Track-based progress information
   currentTrack?: number;  // Current track number (1-based)
   totalTracks?: number;   // Total number of tracks
+  elapsedSeconds?: number; // Media time recorded so far during live capture
+  phase?: string;         // Processing phase, e.g. "transcode", "live"
 }
 
 // --- SSE Event Structure ---